@@ -0,0 +1,207 @@
+// Package beacon reads blob sidecars from a consensus-layer node through the
+// standard beacon HTTP API.
+package beacon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// APIError is a non-200 response from the beacon node.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("beacon API error %d: %s", e.StatusCode, e.Message)
+}
+
+// Sidecar is a blob sidecar as served by /eth/v1/beacon/blob_sidecars.
+type Sidecar struct {
+	Index      uint64
+	Blob       kzg4844.Blob
+	Commitment kzg4844.Commitment
+	Proof      kzg4844.Proof
+}
+
+// VersionedHash returns the hash a blob transaction uses to reference the
+// sidecar's blob.
+func (s *Sidecar) VersionedHash() common.Hash {
+	return kzg4844.CalcBlobHashV1(sha256.New(), &s.Commitment)
+}
+
+// Verify checks the blob against its commitment and proof.
+func (s *Sidecar) Verify() error {
+	return kzg4844.VerifyBlobProof(s.Blob, s.Commitment, s.Proof)
+}
+
+type sidecarJSON struct {
+	Index      string        `json:"index"`
+	Blob       hexutil.Bytes `json:"blob"`
+	Commitment hexutil.Bytes `json:"kzg_commitment"`
+	Proof      hexutil.Bytes `json:"kzg_proof"`
+}
+
+func (s *Sidecar) UnmarshalJSON(input []byte) error {
+	var dec sidecarJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	index, err := strconv.ParseUint(dec.Index, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid sidecar index %q", dec.Index)
+	}
+	if len(dec.Blob) != len(s.Blob) || len(dec.Commitment) != len(s.Commitment) || len(dec.Proof) != len(s.Proof) {
+		return fmt.Errorf("sidecar %d has malformed blob, commitment or proof", index)
+	}
+	s.Index = index
+	copy(s.Blob[:], dec.Blob)
+	copy(s.Commitment[:], dec.Commitment)
+	copy(s.Proof[:], dec.Proof)
+	return nil
+}
+
+func (s Sidecar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sidecarJSON{
+		Index:      strconv.FormatUint(s.Index, 10),
+		Blob:       s.Blob[:],
+		Commitment: s.Commitment[:],
+		Proof:      s.Proof[:],
+	})
+}
+
+// Client talks to a single beacon node.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient returns a client for the beacon node at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+}
+
+// GenesisTime returns the unix time of slot 0.
+func (c *Client) GenesisTime(ctx context.Context) (uint64, error) {
+	var genesis struct {
+		GenesisTime string `json:"genesis_time"`
+	}
+	if err := c.get(ctx, "/eth/v1/beacon/genesis", &genesis); err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(genesis.GenesisTime, 10, 64)
+}
+
+// SecondsPerSlot returns the chain's slot duration.
+func (c *Client) SecondsPerSlot(ctx context.Context) (uint64, error) {
+	var spec struct {
+		SecondsPerSlot string `json:"SECONDS_PER_SLOT"`
+	}
+	if err := c.get(ctx, "/eth/v1/config/spec", &spec); err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(spec.SecondsPerSlot, 10, 64)
+}
+
+// SlotAt maps an execution block timestamp to the slot that produced it.
+func (c *Client) SlotAt(ctx context.Context, timestamp uint64) (uint64, error) {
+	genesis, err := c.GenesisTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	perSlot, err := c.SecondsPerSlot(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if timestamp < genesis || perSlot == 0 {
+		return 0, fmt.Errorf("timestamp %d precedes genesis at %d", timestamp, genesis)
+	}
+	return (timestamp - genesis) / perSlot, nil
+}
+
+// BlobSidecars returns every sidecar of a block. blockID is a slot number, a
+// block root, or one of "head", "genesis" and "finalized".
+func (c *Client) BlobSidecars(ctx context.Context, blockID string) ([]Sidecar, error) {
+	var sidecars []Sidecar
+	if err := c.get(ctx, "/eth/v1/beacon/blob_sidecars/"+blockID, &sidecars); err != nil {
+		return nil, err
+	}
+	return sidecars, nil
+}
+
+// SidecarsByVersionedHash fetches the sidecars of slot, picks the ones
+// matching hashes, verifies them and returns them in the order of hashes.
+func (c *Client) SidecarsByVersionedHash(ctx context.Context, slot uint64, hashes []common.Hash) ([]Sidecar, error) {
+	sidecars, err := c.BlobSidecars(ctx, strconv.FormatUint(slot, 10))
+	if err != nil {
+		return nil, err
+	}
+	byHash := make(map[common.Hash]*Sidecar, len(sidecars))
+	for i := range sidecars {
+		byHash[sidecars[i].VersionedHash()] = &sidecars[i]
+	}
+
+	selected := make([]Sidecar, len(hashes))
+	for i, hash := range hashes {
+		sidecar, ok := byHash[hash]
+		if !ok {
+			return nil, fmt.Errorf("slot %d has no sidecar for blob %s", slot, hash.Hex())
+		}
+		if err := sidecar.Verify(); err != nil {
+			return nil, fmt.Errorf("sidecar %d of slot %d failed verification: %v", sidecar.Index, slot, err)
+		}
+		selected[i] = *sidecar
+	}
+	return selected, nil
+}
+
+// get fetches path and decodes the "data" field of the response into out.
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("failed to decode %s response: %v", path, err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to decode %s data: %v", path, err)
+	}
+	return nil
+}
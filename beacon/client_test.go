@@ -0,0 +1,202 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// fakeServer is an in-process stand-in for a beacon node. It serves the
+// genesis, spec and blob sidecar endpoints from memory.
+type fakeServer struct {
+	server *httptest.Server
+
+	genesisTime    uint64
+	secondsPerSlot uint64
+
+	mu       sync.Mutex
+	sidecars map[uint64][]Sidecar
+	raw      map[uint64]string // served as is instead of sidecars
+}
+
+// newFakeServer starts a fake beacon node that is closed with the test.
+func newFakeServer(t *testing.T, genesisTime, secondsPerSlot uint64) *fakeServer {
+	f := &fakeServer{
+		genesisTime:    genesisTime,
+		secondsPerSlot: secondsPerSlot,
+		sidecars:       make(map[uint64][]Sidecar),
+		raw:            make(map[uint64]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eth/v1/beacon/genesis", f.handleGenesis)
+	mux.HandleFunc("/eth/v1/config/spec", f.handleSpec)
+	mux.HandleFunc("/eth/v1/beacon/blob_sidecars/", f.handleBlobSidecars)
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// slotTime returns the timestamp an execution block built in slot would have.
+func (f *fakeServer) slotTime(slot uint64) uint64 {
+	return f.genesisTime + slot*f.secondsPerSlot
+}
+
+// addSidecar appends the blobs of sidecar to those served for slot.
+func (f *fakeServer) addSidecar(slot uint64, sidecar *types.BlobTxSidecar) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range sidecar.Blobs {
+		f.sidecars[slot] = append(f.sidecars[slot], Sidecar{
+			Index:      uint64(len(f.sidecars[slot])),
+			Blob:       sidecar.Blobs[i],
+			Commitment: sidecar.Commitments[i],
+			Proof:      sidecar.Proofs[i],
+		})
+	}
+}
+
+func (f *fakeServer) handleGenesis(w http.ResponseWriter, r *http.Request) {
+	writeData(w, map[string]string{
+		"genesis_time": strconv.FormatUint(f.genesisTime, 10),
+	})
+}
+
+func (f *fakeServer) handleSpec(w http.ResponseWriter, r *http.Request) {
+	writeData(w, map[string]string{
+		"SECONDS_PER_SLOT": strconv.FormatUint(f.secondsPerSlot, 10),
+	})
+}
+
+func (f *fakeServer) handleBlobSidecars(w http.ResponseWriter, r *http.Request) {
+	slot, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/eth/v1/beacon/blob_sidecars/"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid block id")
+		return
+	}
+	f.mu.Lock()
+	sidecars, ok := f.sidecars[slot]
+	raw, isRaw := f.raw[slot]
+	f.mu.Unlock()
+	if isRaw {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(raw))
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "block not found")
+		return
+	}
+	writeData(w, sidecars)
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "message": message})
+}
+
+func newTestSidecar(t *testing.T, fill byte) *types.BlobTxSidecar {
+	var blob kzg4844.Blob
+	for i := 1; i < len(blob); i += 32 {
+		blob[i] = fill
+	}
+	commitment, err := kzg4844.BlobToCommitment(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := kzg4844.ComputeBlobProof(blob, commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &types.BlobTxSidecar{Blobs: []kzg4844.Blob{blob}, Commitments: []kzg4844.Commitment{commitment}, Proofs: []kzg4844.Proof{proof}}
+}
+
+func TestClientBlobSidecars(t *testing.T) {
+	fake := newFakeServer(t, 1000, 12)
+	first, second := newTestSidecar(t, 1), newTestSidecar(t, 2)
+	fake.addSidecar(7, first)
+	fake.addSidecar(7, second)
+	client := NewClient(fake.server.URL + "/")
+
+	sidecars, err := client.BlobSidecars(context.Background(), "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sidecars) != 2 {
+		t.Fatalf("got %d sidecars, want 2", len(sidecars))
+	}
+	for i, want := range []*types.BlobTxSidecar{first, second} {
+		got := sidecars[i]
+		if got.Index != uint64(i) || got.Blob != want.Blobs[0] || got.Commitment != want.Commitments[0] || got.Proof != want.Proofs[0] {
+			t.Errorf("sidecar %d does not match what was served", i)
+		}
+	}
+
+	slot, err := client.SlotAt(context.Background(), fake.slotTime(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slot != 7 {
+		t.Fatalf("slot %d, want 7", slot)
+	}
+	selected, err := client.SidecarsByVersionedHash(context.Background(), slot, []common.Hash{sidecars[1].VersionedHash(), sidecars[0].VersionedHash()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected[0].Index != 1 || selected[1].Index != 0 {
+		t.Fatalf("selected sidecars %d and %d, want 1 and 0", selected[0].Index, selected[1].Index)
+	}
+}
+
+func TestClientBlobSidecarsNotFound(t *testing.T) {
+	fake := newFakeServer(t, 1000, 12)
+	_, err := NewClient(fake.server.URL).BlobSidecars(context.Background(), "7")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %v, want an APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "block not found" {
+		t.Fatalf("got %v", apiErr)
+	}
+}
+
+func TestClientBlobSidecarsBadJSON(t *testing.T) {
+	tests := map[string]string{
+		"truncated":      `{"data": [`,
+		"not a list":     `{"data": {"index": "0"}}`,
+		"bad index":      `{"data": [{"index": "x", "blob": "0x", "kzg_commitment": "0x", "kzg_proof": "0x"}]}`,
+		"short blob":     `{"data": [{"index": "0", "blob": "0x00", "kzg_commitment": "0x", "kzg_proof": "0x"}]}`,
+		"not hex":        `{"data": [{"index": "0", "blob": "zz"}]}`,
+		"missing fields": `{"data": [{}]}`,
+	}
+	fake := newFakeServer(t, 1000, 12)
+	client := NewClient(fake.server.URL)
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			fake.mu.Lock()
+			fake.raw[7] = body
+			fake.mu.Unlock()
+
+			if sidecars, err := client.BlobSidecars(context.Background(), "7"); err == nil {
+				t.Fatalf("decoded %d sidecars from %s", len(sidecars), body)
+			}
+		})
+	}
+}
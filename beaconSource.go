@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"blob/beacon"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// beaconBlobSource reads blobs of included transactions back from a beacon
+// node. With an archive set, blobs already archived are served from it and
+// blobs fetched are added to it, so they stay available after the beacon node
+// prunes them.
+type beaconBlobSource struct {
+	client  chainClient
+	beacon  *beacon.Client
+	archive *blobArchive
+}
+
+// BlobsByTx implements BlobSource.
+func (s *beaconBlobSource) BlobsByTx(ctx context.Context, txHash common.Hash) ([]kzg4844.Blob, error) {
+	if s.archive != nil {
+		if blobs, err := s.archive.BlobsByTx(ctx, txHash); err == nil {
+			return blobs, nil
+		}
+	}
+
+	tx, pending, err := s.client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %v", err)
+	}
+	if pending {
+		return nil, fmt.Errorf("transaction %s is still pending", txHash.Hex())
+	}
+	if len(tx.BlobHashes()) == 0 {
+		return nil, fmt.Errorf("transaction %s carries no blobs", txHash.Hex())
+	}
+
+	receipt, err := s.client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch receipt: %v", err)
+	}
+	header, err := s.client.HeaderByHash(ctx, receipt.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block header: %v", err)
+	}
+	slot, err := s.beacon.SlotAt(ctx, header.Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map block %d to a slot: %v", header.Number, err)
+	}
+
+	sidecars, err := s.beacon.SidecarsByVersionedHash(ctx, slot, tx.BlobHashes())
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := make([]kzg4844.Blob, len(sidecars))
+	for i, sidecar := range sidecars {
+		blobs[i] = sidecar.Blob
+		if s.archive == nil {
+			continue
+		}
+		err := s.archive.Put(&archivedBlob{
+			Blob:        sidecar.Blob,
+			Commitment:  sidecar.Commitment,
+			Proof:       sidecar.Proof,
+			TxHash:      txHash,
+			Index:       i,
+			BlockNumber: header.Number.Uint64(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return blobs, nil
+}
+
+// printFetchedResult reassembles the result posted by the given transactions
+// and prints it.
+func printFetchedResult(ctx context.Context, source BlobSource, txHashes []string) error {
+	if len(txHashes) == 0 {
+		return fmt.Errorf("no transaction hashes given")
+	}
+	hashes := make([]common.Hash, len(txHashes))
+	for i, hash := range txHashes {
+		hashes[i] = common.HexToHash(hash)
+	}
+
+	payload, err := reassemblePayload(ctx, source, hashes)
+	if err != nil {
+		return err
+	}
+	root, matrix, requestId, err := deserializeResult(payload)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("request id: %v\n", requestId)
+	fmt.Printf("root:       0x%x\n", root)
+	for _, row := range matrix {
+		fmt.Printf("            %v\n", row)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+func TestBeaconBlobSourcePrefersArchive(t *testing.T) {
+	archive, err := openBlobArchive(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blob kzg4844.Blob
+	blob[1] = 2
+	commitment, _ := kzg4844.BlobToCommitment(blob)
+	proof, _ := kzg4844.ComputeBlobProof(blob, commitment)
+	txHash := common.HexToHash("0x02")
+	if err := archive.Put(&archivedBlob{Blob: blob, Commitment: commitment, Proof: proof, TxHash: txHash, BlockNumber: 9}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither node is set, so any lookup beyond the archive would panic.
+	source := &beaconBlobSource{archive: archive}
+	blobs, err := source.BlobsByTx(context.Background(), txHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 1 || blobs[0] != blob {
+		t.Fatalf("got %d blobs from the archive", len(blobs))
+	}
+}
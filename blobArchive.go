@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// archivedBlob is a blob together with what is needed to prove and locate it
+// after consensus clients have pruned it.
+type archivedBlob struct {
+	VersionedHash common.Hash
+	Blob          kzg4844.Blob
+	Commitment    kzg4844.Commitment
+	Proof         kzg4844.Proof
+	TxHash        common.Hash
+	Index         int    // position of the blob in its transaction
+	BlockNumber   uint64 // zero until the transaction is included
+	RequestId     *big.Int
+}
+
+// archiveMeta is the JSON stored next to each raw blob: what proves it.
+type archiveMeta struct {
+	VersionedHash common.Hash   `json:"versionedHash"`
+	Commitment    hexutil.Bytes `json:"commitment"`
+	Proof         hexutil.Bytes `json:"proof"`
+}
+
+// archiveRecord is one posting of a blob in the archive index. The same blob
+// can be posted by several transactions, replacements of one another or not,
+// so records are keyed by versioned hash and transaction hash together.
+type archiveRecord struct {
+	VersionedHash common.Hash    `json:"versionedHash"`
+	TxHash        common.Hash    `json:"txHash"`
+	Index         int            `json:"index"`
+	BlockNumber   hexutil.Uint64 `json:"blockNumber"`
+	RequestId     *hexutil.Big   `json:"requestId,omitempty"`
+}
+
+type archiveKey struct {
+	vh     common.Hash
+	txHash common.Hash
+}
+
+// blobArchive is a content-addressed store on local disk. Each blob is kept
+// once as <versioned hash>.blob with its commitment and proof in
+// <versioned hash>.json. index.json lists every transaction that posted each
+// blob, so lookups only read and re-verify the blobs they return.
+type blobArchive struct {
+	dir string
+
+	mu      sync.Mutex
+	records []*archiveRecord
+	byKey   map[archiveKey]*archiveRecord
+}
+
+func openBlobArchive(dir string) (*blobArchive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob archive: %v", err)
+	}
+	a := &blobArchive{dir: dir, byKey: make(map[archiveKey]*archiveRecord)}
+	raw, err := os.ReadFile(a.indexPath())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read blob archive index: %v", err)
+	default:
+		if err := json.Unmarshal(raw, &a.records); err != nil {
+			return nil, fmt.Errorf("failed to decode blob archive index: %v", err)
+		}
+		for _, rec := range a.records {
+			a.byKey[archiveKey{rec.VersionedHash, rec.TxHash}] = rec
+		}
+	}
+	return a, nil
+}
+
+func (a *blobArchive) path(vh common.Hash, ext string) string {
+	return filepath.Join(a.dir, vh.Hex()+ext)
+}
+
+func (a *blobArchive) indexPath() string {
+	return filepath.Join(a.dir, "index.json")
+}
+
+// Put stores entry. A blob already archived for the same transaction is
+// updated; what entry leaves unset, its request id or block number, is kept
+// from the earlier record. Postings of the blob by other transactions are
+// left alone. The versioned hash is always derived from the commitment.
+func (a *blobArchive) Put(entry *archivedBlob) error {
+	entry.VersionedHash = kzg4844.CalcBlobHashV1(sha256.New(), &entry.Commitment)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := os.Stat(a.path(entry.VersionedHash, ".blob")); err != nil {
+		metaJSON, err := json.MarshalIndent(archiveMeta{
+			VersionedHash: entry.VersionedHash,
+			Commitment:    entry.Commitment[:],
+			Proof:         entry.Proof[:],
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		// The metadata goes first: a blob file is only ever read with it.
+		if err := writeFileAtomic(a.path(entry.VersionedHash, ".json"), metaJSON); err != nil {
+			return fmt.Errorf("failed to store metadata of %s: %v", entry.VersionedHash.Hex(), err)
+		}
+		if err := writeFileAtomic(a.path(entry.VersionedHash, ".blob"), entry.Blob[:]); err != nil {
+			return fmt.Errorf("failed to store blob %s: %v", entry.VersionedHash.Hex(), err)
+		}
+	}
+
+	key := archiveKey{entry.VersionedHash, entry.TxHash}
+	rec, ok := a.byKey[key]
+	if !ok {
+		rec = &archiveRecord{VersionedHash: entry.VersionedHash, TxHash: entry.TxHash}
+		a.byKey[key] = rec
+		a.records = append(a.records, rec)
+	}
+	rec.Index = entry.Index
+	if entry.BlockNumber != 0 {
+		rec.BlockNumber = hexutil.Uint64(entry.BlockNumber)
+	}
+	if entry.RequestId != nil {
+		rec.RequestId = (*hexutil.Big)(entry.RequestId)
+	}
+	return a.saveIndex()
+}
+
+func (a *blobArchive) saveIndex() error {
+	raw, err := json.MarshalIndent(a.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(a.indexPath(), raw); err != nil {
+		return fmt.Errorf("failed to save blob archive index: %v", err)
+	}
+	return nil
+}
+
+// PutTransaction stores every blob of a blob transaction.
+func (a *blobArchive) PutTransaction(tx *types.Transaction, requestId *big.Int, blockNumber uint64) error {
+	sidecar := tx.BlobTxSidecar()
+	if sidecar == nil {
+		return fmt.Errorf("transaction %s has no sidecar to archive", tx.Hash().Hex())
+	}
+	for i := range sidecar.Blobs {
+		err := a.Put(&archivedBlob{
+			Blob:        sidecar.Blobs[i],
+			Commitment:  sidecar.Commitments[i],
+			Proof:       sidecar.Proofs[i],
+			TxHash:      tx.Hash(),
+			Index:       i,
+			BlockNumber: blockNumber,
+			RequestId:   requestId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads the blob rec refers to and re-verifies it.
+func (a *blobArchive) load(rec archiveRecord) (*archivedBlob, error) {
+	vh := rec.VersionedHash
+	meta, err := a.readMeta(vh)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(a.path(vh, ".blob"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %v", vh.Hex(), err)
+	}
+	if len(raw) != len(kzg4844.Blob{}) || len(meta.Commitment) != len(kzg4844.Commitment{}) || len(meta.Proof) != len(kzg4844.Proof{}) {
+		return nil, fmt.Errorf("archived blob %s is malformed", vh.Hex())
+	}
+
+	entry := &archivedBlob{
+		VersionedHash: vh,
+		TxHash:        rec.TxHash,
+		Index:         rec.Index,
+		BlockNumber:   uint64(rec.BlockNumber),
+		RequestId:     (*big.Int)(rec.RequestId),
+	}
+	copy(entry.Blob[:], raw)
+	copy(entry.Commitment[:], meta.Commitment)
+	copy(entry.Proof[:], meta.Proof)
+
+	if got := common.Hash(kzg4844.CalcBlobHashV1(sha256.New(), &entry.Commitment)); got != vh {
+		return nil, fmt.Errorf("archived blob %s has commitment for %s", vh.Hex(), got.Hex())
+	}
+	if err := kzg4844.VerifyBlobProof(entry.Blob, entry.Commitment, entry.Proof); err != nil {
+		return nil, fmt.Errorf("archived blob %s failed verification: %v", vh.Hex(), err)
+	}
+	return entry, nil
+}
+
+// ByRequestId returns every archived blob of a request, in transaction order.
+func (a *blobArchive) ByRequestId(requestId *big.Int) ([]*archivedBlob, error) {
+	return a.find(func(rec *archiveRecord) bool {
+		return rec.RequestId != nil && (*big.Int)(rec.RequestId).Cmp(requestId) == 0
+	})
+}
+
+// ByTxHash returns the archived blobs of a transaction, in order.
+func (a *blobArchive) ByTxHash(txHash common.Hash) ([]*archivedBlob, error) {
+	return a.find(func(rec *archiveRecord) bool {
+		return rec.TxHash == txHash
+	})
+}
+
+// BlobsByTx implements BlobSource.
+func (a *blobArchive) BlobsByTx(ctx context.Context, txHash common.Hash) ([]kzg4844.Blob, error) {
+	entries, err := a.ByTxHash(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no archived blobs for transaction %s", txHash.Hex())
+	}
+	blobs := make([]kzg4844.Blob, len(entries))
+	for i, entry := range entries {
+		if entry.Index != i {
+			return nil, fmt.Errorf("archive is missing blob %d of transaction %s", i, txHash.Hex())
+		}
+		blobs[i] = entry.Blob
+	}
+	return blobs, nil
+}
+
+func (a *blobArchive) find(match func(*archiveRecord) bool) ([]*archivedBlob, error) {
+	a.mu.Lock()
+	var matches []archiveRecord
+	for _, rec := range a.records {
+		if match(rec) {
+			matches = append(matches, *rec)
+		}
+	}
+	a.mu.Unlock()
+
+	entries := make([]*archivedBlob, len(matches))
+	for i, rec := range matches {
+		entry, err := a.load(rec)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = entry
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		x, y := entries[i], entries[j]
+		if x.BlockNumber != y.BlockNumber {
+			return x.BlockNumber < y.BlockNumber
+		}
+		if x.TxHash != y.TxHash {
+			return bytes.Compare(x.TxHash[:], y.TxHash[:]) < 0
+		}
+		return x.Index < y.Index
+	})
+	return entries, nil
+}
+
+func (a *blobArchive) readMeta(vh common.Hash) (*archiveMeta, error) {
+	raw, err := os.ReadFile(a.path(vh, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("blob %s is not archived", vh.Hex())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s: %v", vh.Hex(), err)
+	}
+	var meta archiveMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata of %s: %v", vh.Hex(), err)
+	}
+	if meta.VersionedHash != vh {
+		return nil, fmt.Errorf("metadata of %s describes %s", vh.Hex(), meta.VersionedHash.Hex())
+	}
+	return &meta, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place,
+// so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
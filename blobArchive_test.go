@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// newArchiveTestBlob returns a blob with its commitment and proof.
+func newArchiveTestBlob(t *testing.T, fill byte) (kzg4844.Blob, kzg4844.Commitment, kzg4844.Proof) {
+	t.Helper()
+	var blob kzg4844.Blob
+	blob[1] = fill
+	commitment, err := kzg4844.BlobToCommitment(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := kzg4844.ComputeBlobProof(blob, commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob, commitment, proof
+}
+
+func TestBlobArchivePutKeepsRequestId(t *testing.T) {
+	archive, err := openBlobArchive(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, commitment, proof := newArchiveTestBlob(t, 1)
+	txHash := common.HexToHash("0x01")
+
+	// Posted with its request id, then fetched back from the beacon node.
+	posted := &archivedBlob{Blob: blob, Commitment: commitment, Proof: proof, TxHash: txHash, RequestId: big.NewInt(7)}
+	if err := archive.Put(posted); err != nil {
+		t.Fatal(err)
+	}
+	fetched := &archivedBlob{Blob: blob, Commitment: commitment, Proof: proof, TxHash: txHash, BlockNumber: 42}
+	if err := archive.Put(fetched); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := archive.ByRequestId(big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].BlockNumber != 42 {
+		t.Fatalf("found %d entries for request 7", len(entries))
+	}
+}
+
+func TestBlobArchiveKeepsEveryPosting(t *testing.T) {
+	dir := t.TempDir()
+	archive, err := openBlobArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, commitment, proof := newArchiveTestBlob(t, 1)
+	original, replacement, other := common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")
+
+	// A replacement posts the same blob for the same request, and a later
+	// request happens to post it again.
+	for _, entry := range []*archivedBlob{
+		{Blob: blob, Commitment: commitment, Proof: proof, TxHash: original, RequestId: big.NewInt(7)},
+		{Blob: blob, Commitment: commitment, Proof: proof, TxHash: replacement, RequestId: big.NewInt(7), BlockNumber: 40},
+		{Blob: blob, Commitment: commitment, Proof: proof, TxHash: other, RequestId: big.NewInt(8), BlockNumber: 50},
+	} {
+		if err := archive.Put(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Everything is still there after reopening.
+	if archive, err = openBlobArchive(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, txHash := range []common.Hash{original, replacement, other} {
+		blobs, err := archive.BlobsByTx(context.Background(), txHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blobs) != 1 || blobs[0] != blob {
+			t.Fatalf("transaction %s has %d blobs", txHash.Hex(), len(blobs))
+		}
+	}
+	entries, err := archive.ByRequestId(big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].TxHash != original || entries[1].TxHash != replacement {
+		t.Fatalf("request 7 has %d postings", len(entries))
+	}
+	if entries, err := archive.ByRequestId(big.NewInt(8)); err != nil || len(entries) != 1 || entries[0].TxHash != other {
+		t.Fatalf("request 8 has %d postings (%v)", len(entries), err)
+	}
+}
+
+func TestBlobArchiveRejectsCorruption(t *testing.T) {
+	blob, commitment, proof := newArchiveTestBlob(t, 1)
+	_, otherCommitment, otherProof := newArchiveTestBlob(t, 2)
+	txHash := common.HexToHash("0x01")
+
+	tests := map[string]func(t *testing.T, archive *blobArchive, vh common.Hash){
+		"blob": func(t *testing.T, archive *blobArchive, vh common.Hash) {
+			raw, err := os.ReadFile(archive.path(vh, ".blob"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			raw[100] ^= 1
+			if err := os.WriteFile(archive.path(vh, ".blob"), raw, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"truncated blob": func(t *testing.T, archive *blobArchive, vh common.Hash) {
+			if err := os.Truncate(archive.path(vh, ".blob"), 1000); err != nil {
+				t.Fatal(err)
+			}
+		},
+		"proof": func(t *testing.T, archive *blobArchive, vh common.Hash) {
+			writeArchiveMeta(t, archive, archiveMeta{VersionedHash: vh, Commitment: commitment[:], Proof: otherProof[:]})
+		},
+		"commitment": func(t *testing.T, archive *blobArchive, vh common.Hash) {
+			writeArchiveMeta(t, archive, archiveMeta{VersionedHash: vh, Commitment: otherCommitment[:], Proof: proof[:]})
+		},
+	}
+	for name, corrupt := range tests {
+		t.Run(name, func(t *testing.T) {
+			archive, err := openBlobArchive(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := &archivedBlob{Blob: blob, Commitment: commitment, Proof: proof, TxHash: txHash}
+			if err := archive.Put(entry); err != nil {
+				t.Fatal(err)
+			}
+			corrupt(t, archive, entry.VersionedHash)
+			if blobs, err := archive.BlobsByTx(context.Background(), txHash); err == nil {
+				t.Fatalf("read back %d blobs from a corrupted archive", len(blobs))
+			}
+		})
+	}
+}
+
+func writeArchiveMeta(t *testing.T, archive *blobArchive, meta archiveMeta) {
+	t.Helper()
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive.path(meta.VersionedHash, ".json"), raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// blobFeeStrategy picks the maximum fee per blob gas for a new transaction,
+// given the latest block header.
+type blobFeeStrategy interface {
+	BlobFeeCap(head *types.Header) (*uint256.Int, error)
+}
+
+// fixedBlobFee always bids the same cap.
+type fixedBlobFee struct {
+	Cap *uint256.Int
+}
+
+func (s fixedBlobFee) BlobFeeCap(head *types.Header) (*uint256.Int, error) {
+	return new(uint256.Int).Set(s.Cap), nil
+}
+
+// projectedBlobFee prices blob gas from the head's excess blob gas, as
+// EIP-4844 does, for the block Blocks ahead of head. Every block in between is
+// assumed to be full, which is the fastest the fee can rise. The result is
+// scaled by Multiplier for extra headroom.
+type projectedBlobFee struct {
+	Blocks     uint64
+	Multiplier float64
+}
+
+func (s projectedBlobFee) BlobFeeCap(head *types.Header) (*uint256.Int, error) {
+	if head.ExcessBlobGas == nil || head.BlobGasUsed == nil {
+		return nil, fmt.Errorf("block %v predates blob transactions", head.Number)
+	}
+	if s.Multiplier < 1 {
+		return nil, fmt.Errorf("blob fee multiplier %v is below 1", s.Multiplier)
+	}
+
+	excess := eip4844.CalcExcessBlobGas(*head.ExcessBlobGas, *head.BlobGasUsed)
+	for i := uint64(1); i < s.Blocks; i++ {
+		excess = eip4844.CalcExcessBlobGas(excess, params.MaxBlobGasPerBlock)
+	}
+
+	fee := new(big.Float).SetInt(eip4844.CalcBlobFee(excess))
+	fee.Mul(fee, big.NewFloat(s.Multiplier))
+	capped, _ := fee.Int(nil)
+	if capped.Cmp(big.NewInt(params.BlobTxMinBlobGasprice)) < 0 {
+		capped.SetInt64(params.BlobTxMinBlobGasprice)
+	}
+	feeCap, overflow := uint256.FromBig(capped)
+	if overflow {
+		return nil, fmt.Errorf("projected blob fee %v overflows", capped)
+	}
+	return feeCap, nil
+}
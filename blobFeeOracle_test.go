@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+func TestProjectedBlobFee(t *testing.T) {
+	full := uint64(params.MaxBlobGasPerBlock)
+	target := uint64(params.BlobTxTargetBlobGasPerBlock)
+	step := full - target // excess gained per full block
+
+	// The fee vectors of EIP-4844: excess 2314057 still costs 1 wei per blob
+	// gas, 2314058 costs 2 and 10 MiB costs 23.
+	tests := []struct {
+		name      string
+		excess    uint64
+		used      uint64
+		fee       projectedBlobFee
+		want      uint64
+		wantError bool
+	}{
+		{name: "empty chain", fee: projectedBlobFee{Blocks: 1, Multiplier: 1}, want: 1},
+		{name: "below target", excess: target, used: target - 1, fee: projectedBlobFee{Blocks: 1, Multiplier: 1}, want: 1},
+		{name: "just below a step", excess: 2314057 - step, used: full, fee: projectedBlobFee{Blocks: 1, Multiplier: 1}, want: 1},
+		{name: "first step", excess: 2314058 - step, used: full, fee: projectedBlobFee{Blocks: 1, Multiplier: 1}, want: 2},
+		{name: "excess carries over", excess: 2314058 - 3*step, used: full, fee: projectedBlobFee{Blocks: 3, Multiplier: 1}, want: 2},
+		{name: "one block short", excess: 2314058 - 3*step, used: full, fee: projectedBlobFee{Blocks: 2, Multiplier: 1}, want: 1},
+		{name: "10 MiB", excess: 10*1024*1024 - step, used: full, fee: projectedBlobFee{Blocks: 1, Multiplier: 1}, want: 23},
+		{name: "multiplier", excess: 10*1024*1024 - step, used: full, fee: projectedBlobFee{Blocks: 1, Multiplier: 1.5}, want: 34},
+		{name: "multiplier below 1", fee: projectedBlobFee{Blocks: 1, Multiplier: 0.5}, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excess, used := tt.excess, tt.used
+			head := &types.Header{ExcessBlobGas: &excess, BlobGasUsed: &used}
+			got, err := tt.fee.BlobFeeCap(head)
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("got fee cap %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Eq(uint256.NewInt(tt.want)) {
+				t.Fatalf("fee cap %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectedBlobFeeBeforeCancun(t *testing.T) {
+	fee := projectedBlobFee{Blocks: 1, Multiplier: 1}
+	if got, err := fee.BlobFeeCap(&types.Header{}); err == nil {
+		t.Fatalf("priced blob gas at %v before blob transactions", got)
+	}
+}
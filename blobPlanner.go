@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"blob/encoder"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Cancun blob limits. The blob pool rejects transactions carrying more blobs
+// than fit in a block, so both limits are currently the same.
+const (
+	cancunMaxBlobsPerTx    = params.MaxBlobGasPerBlock / params.BlobTxBlobGasPerBlob
+	cancunMaxBlobsPerBlock = params.MaxBlobGasPerBlock / params.BlobTxBlobGasPerBlob
+)
+
+type blobPlanConfig struct {
+	MaxBlobsPerTx    int
+	MaxBlobsPerBlock int
+	Options          encoder.Options
+}
+
+func defaultBlobPlanConfig(opts encoder.Options) blobPlanConfig {
+	return blobPlanConfig{
+		MaxBlobsPerTx:    cancunMaxBlobsPerTx,
+		MaxBlobsPerBlock: cancunMaxBlobsPerBlock,
+		Options:          opts,
+	}
+}
+
+// blobDataAddress receives the parts of a split payload that carry blobs but
+// no call. It has no code, so those transactions cannot revert and cost no
+// more gas than a plain transfer.
+var blobDataAddress = common.Address{}
+
+// plannedBlobTx is one part of a payload split across several transactions.
+type plannedBlobTx struct {
+	Sequence encoder.Sequence
+	Sidecar  *types.BlobTxSidecar
+}
+
+// planBlobPayload compresses payload and splits the result into parts that
+// each fit in a single blob transaction. Every part is framed on its own and
+// carries sequence metadata so the parts can be put back together in any
+// order.
+func planBlobPayload(payload []byte, cfg blobPlanConfig) ([]plannedBlobTx, error) {
+	if cfg.MaxBlobsPerTx <= 0 || cfg.MaxBlobsPerBlock <= 0 {
+		return nil, fmt.Errorf("invalid blob limits: %d per tx, %d per block", cfg.MaxBlobsPerTx, cfg.MaxBlobsPerBlock)
+	}
+	if cfg.MaxBlobsPerTx > cfg.MaxBlobsPerBlock {
+		cfg.MaxBlobsPerTx = cfg.MaxBlobsPerBlock
+	}
+
+	stored, codec, err := encoder.Compress(payload, cfg.Options.Codec, cfg.Options.Packing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %v", err)
+	}
+
+	partSize := cfg.MaxBlobsPerTx * cfg.Options.Packing.Capacity()
+	parts := 1
+	if len(stored) > 0 {
+		parts = (len(stored) + partSize - 1) / partSize
+	}
+	if parts > math.MaxUint16 {
+		return nil, fmt.Errorf("payload of %d bytes needs %d transactions, more than the %d a sequence can describe", len(stored), parts, math.MaxUint16)
+	}
+
+	batch := binary.BigEndian.Uint64(crypto.Keccak256(payload)[:8])
+
+	plan := make([]plannedBlobTx, 0, parts)
+	for i := 0; i < parts; i++ {
+		start := i * partSize
+		end := start + partSize
+		if end > len(stored) {
+			end = len(stored)
+		}
+
+		opts := cfg.Options
+		opts.Codec = codec
+		opts.Sequence = encoder.Sequence{Batch: batch, Index: uint16(i), Total: uint16(parts)}
+
+		sidecar, err := encoder.Sidecar(stored[start:end], opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode part %d of %d: %v", i, parts, err)
+		}
+		plan = append(plan, plannedBlobTx{Sequence: opts.Sequence, Sidecar: sidecar})
+	}
+	return plan, nil
+}
+
+// createBlobTxs builds one transaction per planned part with consecutive
+// nonces starting at p.Nonce. Only the last part carries input, so the call it
+// makes runs once every part of the data has been posted; the others go to
+// blobDataAddress. Consecutive nonces keep the parts in order, and block
+// builders spread them over blocks as the per-block blob limit requires.
+func createBlobTxs(p *txParams, plan []plannedBlobTx, input []byte) ([]*types.Transaction, error) {
+	txs := make([]*types.Transaction, 0, len(plan))
+	for i, part := range plan {
+		tx, err := createBlobTx(p, p.Nonce+uint64(i), part.Sidecar, input)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(plan)-1 {
+			tx, err = copyBlobTx(tx, func(inner *types.BlobTx) {
+				inner.To = blobDataAddress
+				inner.Data = nil
+				inner.Gas = params.TxGas
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// isBlobDataTx reports whether tx only posts blobs, so there is no call to
+// simulate.
+func isBlobDataTx(tx *types.Transaction) bool {
+	return tx.To() != nil && *tx.To() == blobDataAddress && len(tx.Data()) == 0
+}
+
+// BlobSource returns the blobs that were attached to a transaction.
+type BlobSource interface {
+	BlobsByTx(ctx context.Context, txHash common.Hash) ([]kzg4844.Blob, error)
+}
+
+// reassemblePayload rebuilds a payload split by planBlobPayload from the
+// hashes of its transactions, given in any order.
+func reassemblePayload(ctx context.Context, source BlobSource, txHashes []common.Hash) ([]byte, error) {
+	if len(txHashes) == 0 {
+		return nil, fmt.Errorf("no transactions to reassemble")
+	}
+
+	type part struct {
+		seq   encoder.Sequence
+		codec encoder.Codec
+		data  []byte
+	}
+	parts := make([]part, 0, len(txHashes))
+
+	for _, hash := range txHashes {
+		blobs, err := source.BlobsByTx(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch blobs of %s: %v", hash.Hex(), err)
+		}
+		if len(blobs) == 0 {
+			return nil, fmt.Errorf("transaction %s carries no blobs", hash.Hex())
+		}
+		header, err := encoder.DecodeHeader(&blobs[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode header of %s: %v", hash.Hex(), err)
+		}
+		data, err := encoder.Decode(blobs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode blobs of %s: %v", hash.Hex(), err)
+		}
+		parts = append(parts, part{seq: header.Sequence, codec: header.Codec, data: data})
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].seq.Index < parts[j].seq.Index })
+
+	first := parts[0].seq
+	if first.Total == 0 {
+		if len(parts) != 1 {
+			return nil, fmt.Errorf("unsequenced payload spread over %d transactions", len(parts))
+		}
+		return parts[0].data, nil
+	}
+	if len(parts) != int(first.Total) {
+		return nil, fmt.Errorf("have %d of %d parts of batch %#x", len(parts), first.Total, first.Batch)
+	}
+
+	var stored []byte
+	for i, p := range parts {
+		if p.seq.Batch != first.Batch || p.seq.Total != first.Total || p.codec != parts[0].codec {
+			return nil, fmt.Errorf("part %d belongs to batch %#x, expected %#x", p.seq.Index, p.seq.Batch, first.Batch)
+		}
+		if int(p.seq.Index) != i {
+			return nil, fmt.Errorf("batch %#x is missing part %d", first.Batch, i)
+		}
+		stored = append(stored, p.data...)
+	}
+	payload, err := encoder.Decompress(stored, parts[0].codec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress batch %#x: %v", first.Batch, err)
+	}
+	if binary.BigEndian.Uint64(crypto.Keccak256(payload)[:8]) != first.Batch {
+		return nil, fmt.Errorf("reassembled payload does not match batch %#x", first.Batch)
+	}
+	return payload, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"blob/encoder"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// memoryBlobSource serves the blobs of transactions built by the test.
+type memoryBlobSource map[common.Hash][]kzg4844.Blob
+
+func (s memoryBlobSource) Add(tx *types.Transaction) {
+	if sidecar := tx.BlobTxSidecar(); sidecar != nil {
+		s[tx.Hash()] = sidecar.Blobs
+	}
+}
+
+func (s memoryBlobSource) BlobsByTx(ctx context.Context, txHash common.Hash) ([]kzg4844.Blob, error) {
+	blobs, ok := s[txHash]
+	if !ok {
+		return nil, fmt.Errorf("no blobs known for transaction %s", txHash.Hex())
+	}
+	return blobs, nil
+}
+
+func TestSplitPayloadRoundTrip(t *testing.T) {
+	payload := make([]byte, 2*encoder.Packing31.Capacity()+1000)
+	rand.New(rand.NewSource(1)).Read(payload)
+
+	cfg := defaultBlobPlanConfig(encoder.Options{Codec: encoder.CodecNone, Packing: encoder.Packing31})
+	cfg.MaxBlobsPerTx = 1
+	plan, err := planBlobPayload(payload, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 3 {
+		t.Fatalf("payload split into %d parts, want 3", len(plan))
+	}
+
+	p := &txParams{
+		Nonce:        7,
+		ChainID:      big.NewInt(1),
+		Tip:          big.NewInt(1),
+		MaxFeePerGas: uint256.NewInt(2),
+		BlobFeeCap:   uint256.NewInt(3),
+	}
+	input := []byte{0xde, 0xad}
+	txs, err := createBlobTxs(p, plan, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := make(memoryBlobSource)
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		if tx.Nonce() != 7+uint64(i) {
+			t.Errorf("part %d has nonce %d", i, tx.Nonce())
+		}
+		last := i == len(txs)-1
+		if isBlobDataTx(tx) == last {
+			t.Errorf("part %d: data-only %v, last %v", i, isBlobDataTx(tx), last)
+		}
+		if last && (*tx.To() != common.HexToAddress(TO_ADDRESS) || !bytes.Equal(tx.Data(), input)) {
+			t.Errorf("last part calls %s with %x", tx.To().Hex(), tx.Data())
+		}
+		if !last && tx.Gas() != params.TxGas {
+			t.Errorf("data-only part %d has gas limit %d", i, tx.Gas())
+		}
+		source.Add(tx)
+		hashes[len(txs)-1-i] = tx.Hash() // reassembly must not depend on order
+	}
+
+	got, err := reassemblePayload(context.Background(), source, hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatal("reassembled payload differs")
+	}
+	if _, err := reassemblePayload(context.Background(), source, hashes[1:]); err == nil {
+		t.Fatal("reassembled a payload with a missing part")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"blob/encoder"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// pointEvaluationInputSize is the input length of the EIP-4844
+// point-evaluation precompile at address 0x0a.
+const pointEvaluationInputSize = 192
+
+// cellProof opens a single matrix cell of a blob laid out with
+// encoder.EncodeCells.
+type cellProof struct {
+	Index         int
+	VersionedHash common.Hash
+	Point         kzg4844.Point
+	Claim         kzg4844.Claim
+	Commitment    kzg4844.Commitment
+	Proof         kzg4844.Proof
+}
+
+// matrixCells flattens the result matrix row by row, matching the order of
+// the Merkle leaves.
+func matrixCells(matrix [3][3]*big.Int) []*big.Int {
+	cells := make([]*big.Int, 0, 9)
+	for _, row := range matrix {
+		cells = append(cells, row[:]...)
+	}
+	return cells
+}
+
+// createCellSidecar builds a single-blob sidecar holding one matrix cell per
+// field element.
+func createCellSidecar(matrix [3][3]*big.Int) (*types.BlobTxSidecar, error) {
+	blob, err := encoder.EncodeCells(matrixCells(matrix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lay out matrix cells: %v", err)
+	}
+	return encoder.NewSidecar([]kzg4844.Blob{blob})
+}
+
+// proveCell computes the KZG opening of cell index and checks it locally
+// before handing it out.
+func proveCell(blob *kzg4844.Blob, commitment kzg4844.Commitment, index int) (*cellProof, error) {
+	point, err := encoder.CellPoint(index)
+	if err != nil {
+		return nil, err
+	}
+	proof, claim, err := kzg4844.ComputeProof(*blob, point)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute proof for cell %d: %v", index, err)
+	}
+	p := &cellProof{
+		Index:         index,
+		VersionedHash: kzg4844.CalcBlobHashV1(sha256.New(), &commitment),
+		Point:         point,
+		Claim:         claim,
+		Commitment:    commitment,
+		Proof:         proof,
+	}
+	if err := p.Verify(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Verify checks the opening the same way the point-evaluation precompile does.
+func (p *cellProof) Verify() error {
+	if kzg4844.CalcBlobHashV1(sha256.New(), &p.Commitment) != p.VersionedHash {
+		return fmt.Errorf("cell %d: versioned hash does not match commitment", p.Index)
+	}
+	if err := kzg4844.VerifyProof(p.Commitment, p.Point, p.Claim, p.Proof); err != nil {
+		return fmt.Errorf("cell %d: invalid proof: %v", p.Index, err)
+	}
+	return nil
+}
+
+// PointEvaluationInput returns the precompile input:
+// versioned_hash | z | y | commitment | proof.
+func (p *cellProof) PointEvaluationInput() []byte {
+	input := make([]byte, 0, pointEvaluationInputSize)
+	input = append(input, p.VersionedHash[:]...)
+	input = append(input, p.Point[:]...)
+	input = append(input, p.Claim[:]...)
+	input = append(input, p.Commitment[:]...)
+	input = append(input, p.Proof[:]...)
+	return input
+}
+
+// printCellProof opens cell index of matrix laid out with createCellSidecar
+// and prints the opening and the precompile input.
+func printCellProof(matrix [3][3]*big.Int, index int) error {
+	sidecar, err := createCellSidecar(matrix)
+	if err != nil {
+		return err
+	}
+	proof, err := proveCell(&sidecar.Blobs[0], sidecar.Commitments[0], index)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("cell:           %d\n", proof.Index)
+	fmt.Printf("versioned hash: %s\n", proof.VersionedHash.Hex())
+	fmt.Printf("z:              0x%x\n", proof.Point)
+	fmt.Printf("y:              0x%x\n", proof.Claim)
+	fmt.Printf("commitment:     0x%x\n", proof.Commitment)
+	fmt.Printf("proof:          0x%x\n", proof.Proof)
+	fmt.Printf("precompile:     0x%s\n", hex.EncodeToString(proof.PointEvaluationInput()))
+	return nil
+}
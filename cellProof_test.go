@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"blob/encoder"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+func testCellMatrix() [3][3]*big.Int {
+	var matrix [3][3]*big.Int
+	for i := range matrix {
+		for j := range matrix[i] {
+			matrix[i][j] = big.NewInt(int64(1000*i + 10*j + 7))
+		}
+	}
+	matrix[2][2] = new(big.Int).Lsh(big.NewInt(1), 250)
+	return matrix
+}
+
+func TestCellPointOpensCell(t *testing.T) {
+	cells := matrixCells(testCellMatrix())
+	sidecar, err := createCellSidecar(testCellMatrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, commitment := sidecar.Blobs[0], sidecar.Commitments[0]
+
+	// Cell 9 is past the matrix and holds zero.
+	for i := 0; i <= len(cells); i++ {
+		point, err := encoder.CellPoint(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof, claim, err := kzg4844.ComputeProof(blob, point)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want kzg4844.Claim
+		if i < len(cells) {
+			cells[i].FillBytes(want[:])
+		}
+		if claim != want {
+			t.Fatalf("cell %d opens to 0x%x, want 0x%x", i, claim, want)
+		}
+		if err := kzg4844.VerifyProof(commitment, point, claim, proof); err != nil {
+			t.Fatalf("cell %d: %v", i, err)
+		}
+		want[31] ^= 1
+		if err := kzg4844.VerifyProof(commitment, point, want, proof); err == nil {
+			t.Fatalf("cell %d: proof verified a wrong value", i)
+		}
+	}
+}
+
+func TestCellProofPointEvaluationInput(t *testing.T) {
+	sidecar, err := createCellSidecar(testCellMatrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := proveCell(&sidecar.Blobs[0], sidecar.Commitments[0], 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := p.PointEvaluationInput()
+	if len(input) != pointEvaluationInputSize {
+		t.Fatalf("input is %d bytes, want %d", len(input), pointEvaluationInputSize)
+	}
+	vh := kzg4844.CalcBlobHashV1(sha256.New(), &sidecar.Commitments[0])
+	point, _ := encoder.CellPoint(4)
+	var claim kzg4844.Claim
+	big.NewInt(1017).FillBytes(claim[:])
+	for _, field := range []struct {
+		name       string
+		start, end int
+		want       []byte
+	}{
+		{"versioned hash", 0, 32, vh[:]},
+		{"z", 32, 64, point[:]},
+		{"y", 64, 96, claim[:]},
+		{"commitment", 96, 144, sidecar.Commitments[0][:]},
+		{"proof", 144, 192, p.Proof[:]},
+	} {
+		if got := input[field.start:field.end]; !bytes.Equal(got, field.want) {
+			t.Errorf("%s is 0x%x, want 0x%x", field.name, got, field.want)
+		}
+	}
+
+	p.Proof[0] ^= 1
+	if err := p.Verify(); err == nil {
+		t.Fatal("verified a tampered proof")
+	}
+}
@@ -5,109 +5,135 @@ import (
 	"fmt"
 	"log"
 	"math/big"
-	"os"
-	"strings"
+
+	"blob/rollup"
 
 	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 var contractAddress = "0x"
 
-func CheckLatestRequestId(client *ethclient.Client) (*big.Int, error) {
-
-	parsedABI, address, err := ParseABI()
+func rollupContract(client chainClient) *rollup.Rollup {
+	return rollup.New(common.HexToAddress(contractAddress), client)
+}
 
+// scanRequests returns every request in the log scan saved at scanFile,
+// brought up to date.
+func scanRequests(ctx context.Context, client chainClient, scanFile string, deployment uint64) ([]*requestRecord, error) {
+	scanner, err := openLogScanner(ctx, client, common.HexToAddress(contractAddress), deployment, scanFile, defaultScanConfig())
 	if err != nil {
 		return nil, err
 	}
-
-	query := ethereum.FilterQuery{
-		FromBlock: nil, // Use nil for latest or specify a block number
-		ToBlock:   nil,
-		Addresses: []common.Address{*address},
+	if err := scanner.Scan(ctx); err != nil {
+		return nil, err
 	}
+	return scanner.Requests(), nil
+}
 
-	logs, err := client.FilterLogs(context.Background(), query)
-
+// latestRequestId returns the newest request the contract has received.
+func latestRequestId(ctx context.Context, client chainClient, scanFile string, deployment uint64) (*big.Int, error) {
+	requests, err := scanRequests(ctx, client, scanFile, deployment)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching logs: %v", err)
+		return nil, err
 	}
-
-	var requestId *big.Int
-
-	for _, vLog := range logs {
-		_, err := parsedABI.Unpack("NewReceipt", vLog.Data)
-		if err != nil {
-			log.Printf("Failed to unpack log data: %v", err)
-			continue
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].Sender != (common.Address{}) { // seen its NewReceipt
+			return requests[i].RequestId, nil
 		}
-
-		requestId = big.NewInt(0).SetBytes(vLog.Topics[1].Bytes())
 	}
-
-	return requestId, nil
+	return nil, fmt.Errorf("contract %s has received no requests", contractAddress)
 }
-func GetMatrices(client *ethclient.Client, requestId *big.Int) ([2][3][3]*big.Int, error) {
-	parsedABI, address, err := ParseABI()
-	if err != nil {
-		return [2][3][3]*big.Int{}, fmt.Errorf("failed to parse ABI: %v", err)
-	}
 
-	callData, err := parsedABI.Pack("getMatrices", requestId)
+// checkRequestReceived fails unless the contract logged the NewReceipt of
+// requestId. getMatrices cannot tell: it returns zero matrices for requests
+// that do not exist, and a real request may well multiply to zero.
+func checkRequestReceived(ctx context.Context, client chainClient, scanFile string, deployment uint64, requestId *big.Int) error {
+	requests, err := scanRequests(ctx, client, scanFile, deployment)
 	if err != nil {
-		return [2][3][3]*big.Int{}, fmt.Errorf("failed to pack call data for getMatrices: %v", err)
+		return err
 	}
-
-	msg := ethereum.CallMsg{To: address, Data: callData}
-	res, err := client.CallContract(context.Background(), msg, nil)
-	if err != nil {
-		return [2][3][3]*big.Int{}, fmt.Errorf("failed to call getMatrices: %v", err)
+	for _, r := range requests {
+		if r.RequestId.Cmp(requestId) == 0 && r.Sender != (common.Address{}) {
+			return nil
+		}
 	}
+	return fmt.Errorf("contract %s has not received request %v", contractAddress, requestId)
+}
 
-	var matrices [2][3][3]*big.Int
-	err = parsedABI.UnpackIntoInterface(&matrices, "getMatrices", res)
+// solveRequest reads the matrices of requestId and returns their product and
+// the Merkle root submitResult commits to. See checkRequestReceived for
+// making sure the request exists.
+func solveRequest(client chainClient, requestId *big.Int) ([3][3]*big.Int, []byte, error) {
+	matrices, err := GetMatrices(client, requestId)
 	if err != nil {
-		return [2][3][3]*big.Int{}, fmt.Errorf("failed to unpack response from getMatrices: %v", err)
+		return [3][3]*big.Int{}, nil, fmt.Errorf("failed to read matrices of request %v: %v", requestId, err)
 	}
-
-	return matrices, nil
+	matrixMul, merkleRoot := solveMatrices(matrices)
+	return matrixMul, merkleRoot, nil
 }
 
-func generateSubmitSolutionCalldata(root []byte, matrixMul [3][3]*big.Int, requestId *big.Int) []byte {
-
-	parsedABI, _, err := ParseABI()
+// solveMatrices returns the product of a request's two matrices and the
+// Merkle root submitResult commits to.
+func solveMatrices(matrices [2][3][3]*big.Int) ([3][3]*big.Int, []byte) {
+	matrixMul, singletonArray := MultiplyMatrices(matrices[0], matrices[1])
+	return matrixMul, MerkleTreeRoot(singletonArray[:])
+}
 
+func GetMatrices(client chainClient, requestId *big.Int) ([2][3][3]*big.Int, error) {
+	matrix1, matrix2, err := rollupContract(client).GetMatrices(nil, requestId)
 	if err != nil {
-		log.Fatal("cannot parse abi")
+		return [2][3][3]*big.Int{}, err
 	}
+	return [2][3][3]*big.Int{matrix1, matrix2}, nil
+}
+
+func generateSubmitSolutionCalldata(root []byte, matrixMul [3][3]*big.Int, requestId *big.Int) []byte {
 
 	var root32 [32]byte
 	copy(root32[:], root) // Assuming 'root' is a slice of exactly 32 bytes
 
-	input, err := parsedABI.Pack("submitResult", root32, matrixMul, requestId)
+	input, err := rollup.PackSubmitResult(root32, matrixMul, requestId)
 
 	if err != nil {
-		fmt.Println("here")
 		log.Fatal(err)
 	}
 	return input
 }
 
-func ParseABI() (*abi.ABI, *common.Address, error) {
-	abiPath := "./abi.json"
-	abiBytes, err := os.ReadFile(abiPath)
+// printEvents decodes and prints every event the contract logged between
+// from and to inclusive.
+func printEvents(ctx context.Context, client chainClient, from, to uint64) error {
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{common.HexToAddress(contractAddress)},
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("error reading ABI file: %v", err)
+		return fmt.Errorf("failed to fetch logs in blocks %d-%d: %v", from, to, err)
 	}
-
-	parsedABI, err := abi.JSON(strings.NewReader(string(abiBytes)))
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse contract ABI: %v", err)
+	for _, l := range logs {
+		ev, err := rollup.DecodeEvent(l)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		meta := ev.Metadata()
+		fmt.Printf("block %d tx %s log %d %-18s ", meta.Block, meta.TxHash.Hex(), meta.LogIndex, ev.EventName())
+		switch ev := ev.(type) {
+		case *rollup.DisputeRaised:
+			fmt.Printf("disputer %s request %v\n", ev.Disputer.Hex(), ev.RequestId)
+		case *rollup.NewReceipt:
+			fmt.Printf("sender %s request %v\n", ev.Sender.Hex(), ev.RequestId)
+		case *rollup.OperatorRegistered:
+			fmt.Printf("operator %s stake %v wei\n", ev.Operator.Hex(), ev.StakeAmount)
+		case *rollup.PenaltyApplied:
+			fmt.Printf("solver %s penalties %v\n", ev.Solver.Hex(), ev.Penalties)
+		case *rollup.ResultSubmitted:
+			fmt.Printf("solver %s request %v root %s\n", ev.Solver.Hex(), ev.RequestId, common.Hash(ev.ResultRoot).Hex())
+		case *rollup.SuccessfulDispute:
+			fmt.Printf("disputer %s\n", ev.Disputer.Hex())
+		}
 	}
-
-	address := common.HexToAddress(contractAddress)
-	return &parsedABI, &address, nil
+	return nil
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"blob/rollup"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// matricesClient answers getMatrices with fixed matrices.
+type matricesClient struct {
+	chainClient
+	a, b [3][3]*big.Int
+}
+
+func (c *matricesClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return rollup.ABI.Methods["getMatrices"].Outputs.Pack(c.a, c.b)
+}
+
+func matrixOf(f func(i, j int) int64) [3][3]*big.Int {
+	var m [3][3]*big.Int
+	for i := range m {
+		for j := range m[i] {
+			m[i][j] = big.NewInt(f(i, j))
+		}
+	}
+	return m
+}
+
+func TestSolveRequest(t *testing.T) {
+	identity := matrixOf(func(i, j int) int64 {
+		if i == j {
+			return 1
+		}
+		return 0
+	})
+	client := &matricesClient{a: resultMatrix(), b: identity}
+
+	matrixMul, root, err := solveRequest(client, big.NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range matrixMul {
+		for j := range matrixMul[i] {
+			if matrixMul[i][j].Cmp(client.a[i][j]) != 0 {
+				t.Fatalf("product differs at %d,%d: %v", i, j, matrixMul[i][j])
+			}
+		}
+	}
+	if !bytes.Equal(root, resultMerkleRoot()) {
+		t.Fatalf("root %x, want %x", root, resultMerkleRoot())
+	}
+
+	// A product of zero is a valid result, not a sign of a missing request.
+	zero := matrixOf(func(i, j int) int64 { return 0 })
+	matrixMul, _, err = solveRequest(&matricesClient{a: resultMatrix(), b: zero}, big.NewInt(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matrixMul[1][2].Sign() != 0 {
+		t.Fatalf("product with the zero matrix has %v at 1,2", matrixMul[1][2])
+	}
+}
+
+func TestCheckRequestReceived(t *testing.T) {
+	// Request 5 only has a result logged, as if its receipt came before the
+	// scanned range; request 9 has nothing at all.
+	result := types.Log{
+		Topics:      []common.Hash{rollup.ResultSubmittedID, common.BigToHash(big.NewInt(5))},
+		Data:        make([]byte, 64),
+		BlockNumber: 4,
+	}
+	chain := &logChain{}
+	chain.set(30, newReceiptLog(3, 0, 3), result)
+	scanFile := filepath.Join(t.TempDir(), "scan.json")
+
+	if err := checkRequestReceived(context.Background(), chain, scanFile, 1, big.NewInt(3)); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{5, 9} {
+		if err := checkRequestReceived(context.Background(), chain, scanFile, 1, big.NewInt(id)); err == nil {
+			t.Fatalf("request %d passed as received", id)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package encoder
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// primitiveRootOfUnity generates the multiplicative group of the BLS12-381
+// scalar field, as in the EIP-4844 consensus specs.
+const primitiveRootOfUnity = 7
+
+var (
+	modulus     = new(big.Int).SetBytes(blsModulus[:])
+	domainOnce  sync.Once
+	domainRoot  *big.Int
+	domainShift = bits.UintSize - bits.Len(fieldElementsPerBlob-1)
+)
+
+// EncodeCells lays out values one per field element, in order, without any
+// framing. Each value must be a canonical scalar. Cell i can then be opened
+// with a KZG proof at CellPoint(i).
+func EncodeCells(values []*big.Int) (kzg4844.Blob, error) {
+	var blob kzg4844.Blob
+	if len(values) > fieldElementsPerBlob {
+		return blob, fmt.Errorf("%w: %d cells do not fit in one blob", ErrPayloadTooLarge, len(values))
+	}
+	for i, v := range values {
+		if v.Sign() < 0 || v.Cmp(modulus) >= 0 {
+			return blob, fmt.Errorf("%w: cell %d", ErrNonCanonical, i)
+		}
+		v.FillBytes(blob[i*fieldElementSize : (i+1)*fieldElementSize])
+	}
+	return blob, nil
+}
+
+// CellPoint returns the evaluation point of field element index. Blobs hold
+// the polynomial's values over the 4096th roots of unity in bit-reversed
+// order, so cell i is the value at w^bitrev(i).
+func CellPoint(index int) (kzg4844.Point, error) {
+	var point kzg4844.Point
+	if index < 0 || index >= fieldElementsPerBlob {
+		return point, fmt.Errorf("cell index %d out of range", index)
+	}
+	domainOnce.Do(func() {
+		exp := new(big.Int).Sub(modulus, big.NewInt(1))
+		exp.Div(exp, big.NewInt(fieldElementsPerBlob))
+		domainRoot = new(big.Int).Exp(big.NewInt(primitiveRootOfUnity), exp, modulus)
+	})
+	reversed := bits.Reverse(uint(index)) >> domainShift
+	value := new(big.Int).Exp(domainRoot, new(big.Int).SetUint64(uint64(reversed)), modulus)
+	value.FillBytes(point[:])
+	return point, nil
+}
@@ -0,0 +1,141 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxDecompressedSize bounds how much a compressed payload may expand to, so a
+// malicious blob cannot exhaust memory.
+const maxDecompressedSize = 64 << 20
+
+// codecs lists the codecs CodecAuto tries, in order of preference when they
+// need the same number of blobs.
+var codecs = []Codec{CodecNone, CodecZstd, CodecS2, CodecDeflate}
+
+// ParseCodec maps the names printed by Codec.String back to a codec.
+func ParseCodec(name string) (Codec, error) {
+	if name == CodecAuto.String() {
+		return CodecAuto, nil
+	}
+	for _, c := range codecs {
+		if name == c.String() {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownCodec, name)
+}
+
+func (c Codec) valid() bool {
+	return c == CodecNone || c == CodecZstd || c == CodecS2 || c == CodecDeflate
+}
+
+// Compress applies codec to data. With CodecAuto every codec is tried and the
+// one needing the fewest blobs under packing wins; the codec actually used is
+// returned alongside the output.
+func Compress(data []byte, codec Codec, packing Packing) ([]byte, Codec, error) {
+	if codec != CodecAuto {
+		out, err := compress(data, codec)
+		return out, codec, err
+	}
+
+	var (
+		best      []byte
+		bestCodec Codec
+	)
+	for _, c := range codecs {
+		out, err := compress(data, c)
+		if err != nil {
+			return nil, 0, err
+		}
+		if best == nil || BlobCount(len(out), packing) < BlobCount(len(best), packing) {
+			best, bestCodec = out, c
+		}
+	}
+	return best, bestCodec, nil
+}
+
+func compress(data []byte, codec Codec) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return data, nil
+
+	case CodecZstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		if err != nil {
+			return nil, err
+		}
+		defer enc.Close()
+		return enc.EncodeAll(data, nil), nil
+
+	case CodecS2:
+		return s2.EncodeBest(nil, data), nil
+
+	case CodecDeflate:
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnknownCodec, codec)
+}
+
+// Decompress reverses Compress for a concrete codec.
+func Decompress(data []byte, codec Codec) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return data, nil
+
+	case CodecZstd:
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecompressedSize))
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		out, err := dec.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecompress, err)
+		}
+		return out, nil
+
+	case CodecS2:
+		n, err := s2.DecodedLen(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecompress, err)
+		}
+		if n > maxDecompressedSize {
+			return nil, fmt.Errorf("%w: expands to %d bytes", ErrDecompress, n)
+		}
+		out, err := s2.Decode(nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecompress, err)
+		}
+		return out, nil
+
+	case CodecDeflate:
+		r := flate.NewReader(bytes.NewReader(data))
+		defer r.Close()
+		out, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecompress, err)
+		}
+		if len(out) > maxDecompressedSize {
+			return nil, fmt.Errorf("%w: expands past %d bytes", ErrDecompress, maxDecompressedSize)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnknownCodec, codec)
+}
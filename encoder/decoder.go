@@ -0,0 +1,93 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// blsModulus is the BLS12-381 scalar field modulus in big-endian form. Every
+// field element of a valid blob must be strictly below it.
+var blsModulus = [fieldElementSize]byte{
+	0x73, 0xed, 0xa7, 0x53, 0x29, 0x9d, 0x7d, 0x48,
+	0x33, 0x39, 0xd8, 0x08, 0x09, 0xa1, 0xd8, 0x05,
+	0x53, 0xbd, 0xa4, 0x02, 0xff, 0xfe, 0x5b, 0xfe,
+	0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x01,
+}
+
+// DecodeHeader checks that blob is canonical and returns its framing header.
+func DecodeHeader(blob *kzg4844.Blob) (Header, error) {
+	if err := checkCanonical(blob); err != nil {
+		return Header{}, err
+	}
+	return parseHeader(blob[1:fieldElementSize])
+}
+
+// Decode reverses Encode. The blobs must be given in the order they were
+// produced; missing, extra, reordered or corrupt blobs are reported as a
+// *BlobError wrapping one of the package's sentinel errors.
+//
+// Standalone payloads are decompressed. Parts of a sequence are returned as
+// stored; the caller joins them and calls Decompress with the header's codec.
+func Decode(blobs []kzg4844.Blob) ([]byte, error) {
+	if len(blobs) == 0 {
+		return nil, ErrNoBlobs
+	}
+
+	var first Header
+	for i := range blobs {
+		header, err := DecodeHeader(&blobs[i])
+		if err != nil {
+			return nil, &BlobError{Index: i, Err: err}
+		}
+		if i == 0 {
+			first = header
+		}
+		if int(header.Index) != i {
+			return nil, &BlobError{Index: i, Err: fmt.Errorf("%w: carries index %d", ErrBlobOrder, header.Index)}
+		}
+		if header.Count != first.Count || header.Length != first.Length ||
+			header.Checksum != first.Checksum || header.Codec != first.Codec ||
+			header.Packing != first.Packing || header.Sequence != first.Sequence {
+			return nil, &BlobError{Index: i, Err: ErrHeaderMismatch}
+		}
+	}
+	if len(blobs) < int(first.Count) {
+		return nil, &BlobError{Index: len(blobs), Err: fmt.Errorf("%w: have %d of %d", ErrTruncated, len(blobs), first.Count)}
+	}
+	if len(blobs) > int(first.Count) {
+		return nil, &BlobError{Index: int(first.Count), Err: ErrExtraBlobs}
+	}
+
+	data := make([]byte, 0, first.Length)
+	for i := range blobs {
+		n := int(first.Length) - len(data)
+		if n > first.Packing.Capacity() {
+			n = first.Packing.Capacity()
+		}
+		chunk, err := first.Packing.unpack(&blobs[i], n)
+		if err != nil {
+			return nil, &BlobError{Index: i, Err: err}
+		}
+		data = append(data, chunk...)
+	}
+	if Checksum(data) != first.Checksum {
+		return nil, ErrChecksum
+	}
+	if first.Sequence.Total != 0 {
+		return data, nil
+	}
+	return Decompress(data, first.Codec)
+}
+
+// checkCanonical verifies every field element of blob is below the modulus.
+func checkCanonical(blob *kzg4844.Blob) error {
+	for fe := 0; fe < fieldElementsPerBlob; fe++ {
+		offset := fe * fieldElementSize
+		if bytes.Compare(blob[offset:offset+fieldElementSize], blsModulus[:]) >= 0 {
+			return fmt.Errorf("%w: field element %d", ErrNonCanonical, fe)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// testPayload returns n pseudo-random bytes, the same for every run.
+func testPayload(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(data)
+	return data
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	capacity := Packing31.Capacity()
+	tests := []struct {
+		name string
+		data []byte
+		opts Options
+	}{
+		{"empty", nil, Options{}},
+		{"one byte", []byte{0xff}, Options{}},
+		{"one field element", testPayload(bytesPerFieldElement), Options{}},
+		{"full blob", testPayload(capacity), Options{}},
+		{"one byte over", testPayload(capacity + 1), Options{}},
+		{"three blobs", testPayload(2*capacity + 100), Options{}},
+		{"zstd", bytes.Repeat([]byte("matrix"), 50000), Options{Codec: CodecZstd}},
+		{"s2", bytes.Repeat([]byte("matrix"), 50000), Options{Codec: CodecS2}},
+		{"deflate", bytes.Repeat([]byte("matrix"), 50000), Options{Codec: CodecDeflate}},
+		{"auto", bytes.Repeat([]byte("matrix"), 50000), Options{Codec: CodecAuto}},
+		{"254-bit", testPayload(2*Packing254.Capacity() + 7), Options{Packing: Packing254}},
+		{"254-bit zstd", bytes.Repeat([]byte("matrix"), 50000), Options{Codec: CodecZstd, Packing: Packing254}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobs, err := EncodeWithOptions(tt.data, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Decode(blobs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Fatalf("decoded %d bytes, want %d", len(got), len(tt.data))
+			}
+		})
+	}
+}
+
+func TestDecodeSequencePart(t *testing.T) {
+	part := testPayload(1000)
+	blobs, err := EncodeWithOptions(part, Options{Codec: CodecZstd, Sequence: Sequence{Batch: 9, Index: 1, Total: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, part) {
+		t.Fatal("sequence part was not returned as stored")
+	}
+	header, err := DecodeHeader(&blobs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Codec != CodecZstd || header.Sequence != (Sequence{Batch: 9, Index: 1, Total: 2}) {
+		t.Fatalf("header %+v", header)
+	}
+}
+
+// setHeader overwrites header byte offset of blob with b.
+func setHeader(blob *kzg4844.Blob, offset int, b ...byte) {
+	copy(blob[1+offset:], b)
+}
+
+func setLength(blob *kzg4844.Blob, length uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], length)
+	setHeader(blob, 9, b[:]...)
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	data := testPayload(2*Packing31.Capacity() + 100)
+	tests := []struct {
+		name    string
+		corrupt func([]kzg4844.Blob) []kzg4844.Blob
+		want    error
+		index   int // of the blob the error is reported for, -1 for none
+	}{
+		{
+			name:    "no blobs",
+			corrupt: func([]kzg4844.Blob) []kzg4844.Blob { return nil },
+			want:    ErrNoBlobs,
+			index:   -1,
+		},
+		{
+			name:    "bad magic",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { setHeader(&b[1], 0, 'B', 'O', 'L', 'B'); return b },
+			want:    ErrBadMagic,
+			index:   1,
+		},
+		{
+			name:    "unsupported version",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { setHeader(&b[0], 4, 2); return b },
+			want:    ErrUnsupportedVersion,
+			index:   0,
+		},
+		{
+			name:    "unknown codec",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { setHeader(&b[2], 5, 9); return b },
+			want:    ErrUnknownCodec,
+			index:   2,
+		},
+		{
+			name:    "auto codec in header",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { setHeader(&b[0], 5, byte(CodecAuto)); return b },
+			want:    ErrUnknownCodec,
+			index:   0,
+		},
+		{
+			name:    "unknown packing",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { setHeader(&b[0], 6, 7); return b },
+			want:    ErrUnknownPacking,
+			index:   0,
+		},
+		{
+			name:    "reserved bytes set",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { setHeader(&b[0], 29, 1); return b },
+			want:    ErrCorrupt,
+			index:   0,
+		},
+		{
+			name:    "count does not fit length",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { setHeader(&b[0], 8, 4); return b },
+			want:    ErrCorrupt,
+			index:   0,
+		},
+		{
+			name: "length too short",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob {
+				for i := range b {
+					setLength(&b[i], uint32(len(data)-1))
+				}
+				return b
+			},
+			want:  ErrCorrupt,
+			index: 2,
+		},
+		{
+			name:    "length differs between blobs",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { setLength(&b[1], uint32(len(data)+1)); return b },
+			want:    ErrHeaderMismatch,
+			index:   1,
+		},
+		{
+			name: "non-canonical field element",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob {
+				copy(b[1][5*fieldElementSize:], bytes.Repeat([]byte{0xff}, fieldElementSize))
+				return b
+			},
+			want:  ErrNonCanonical,
+			index: 1,
+		},
+		{
+			name:    "high byte set",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { b[1][5*fieldElementSize] = 1; return b },
+			want:    ErrCorrupt,
+			index:   1,
+		},
+		{
+			name:    "data past the payload",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { b[2][len(b[2])-1] = 1; return b },
+			want:    ErrCorrupt,
+			index:   2,
+		},
+		{
+			name:    "flipped payload bit",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { b[1][3*fieldElementSize+7] ^= 1; return b },
+			want:    ErrChecksum,
+			index:   -1,
+		},
+		{
+			name:    "missing blob",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { return b[:2] },
+			want:    ErrTruncated,
+			index:   2,
+		},
+		{
+			name:    "reordered",
+			corrupt: func(b []kzg4844.Blob) []kzg4844.Blob { b[0], b[1] = b[1], b[0]; return b },
+			want:    ErrBlobOrder,
+			index:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobs, err := Encode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = Decode(tt.corrupt(blobs))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			var blobErr *BlobError
+			switch {
+			case tt.index < 0 && errors.As(err, &blobErr):
+				t.Fatalf("error %v names a blob", err)
+			case tt.index >= 0 && !errors.As(err, &blobErr):
+				t.Fatalf("error %v names no blob", err)
+			case tt.index >= 0 && blobErr.Index != tt.index:
+				t.Fatalf("error names blob %d, want %d", blobErr.Index, tt.index)
+			}
+		})
+	}
+}
+
+func TestDecodeCorruptCompressed(t *testing.T) {
+	blobs, err := EncodeWithOptions(bytes.Repeat([]byte("matrix"), 1000), Options{Codec: CodecZstd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Relabel the zstd stream as deflate, keeping the checksum valid.
+	setHeader(&blobs[0], 5, byte(CodecDeflate))
+	if _, err := Decode(blobs); !errors.Is(err, ErrDecompress) {
+		t.Fatalf("got %v, want %v", err, ErrDecompress)
+	}
+}
@@ -0,0 +1,110 @@
+// Package encoder converts arbitrary byte payloads into EIP-4844 blobs.
+//
+// Every blob starts with a framing Header in its first field element, and the
+// payload follows in the remaining field elements. The header records the
+// Packing used for the payload: either 31 bytes per field element, or 254 bits
+// per field element for about 2.4% more room. Both keep every element below
+// the BLS12-381 scalar modulus. The payload can be compressed first; the codec
+// is recorded in the header as well.
+package encoder
+
+import (
+	"fmt"
+	"hash/crc32"
+	"math"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	fieldElementSize     = 32
+	bytesPerFieldElement = 31
+	fieldElementsPerBlob = params.BlobTxFieldElementsPerBlob
+
+	// MaxBlobs is the largest number of blobs a header can describe.
+	MaxBlobs = math.MaxUint8
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksum returns the CRC-32C checksum stored in the header for data.
+func Checksum(data []byte) uint32 {
+	return crc32.Checksum(data, castagnoli)
+}
+
+// Options controls how Encode lays out a payload.
+type Options struct {
+	Codec    Codec
+	Packing  Packing
+	Sequence Sequence
+}
+
+// BlobCount returns the number of blobs a payload of the given length needs
+// with the given packing.
+func BlobCount(length int, packing Packing) int {
+	if length == 0 {
+		return 1
+	}
+	capacity := packing.Capacity()
+	return (length + capacity - 1) / capacity
+}
+
+// Encode frames data with the default options and packs it into as many
+// blobs as needed.
+func Encode(data []byte) ([]kzg4844.Blob, error) {
+	return EncodeWithOptions(data, Options{})
+}
+
+// EncodeWithOptions compresses data, frames it and packs it into as many
+// blobs as needed.
+//
+// A part of a sequence is only a slice of the batch's compressed stream, so it
+// is stored as given and opts.Codec names the codec of the whole batch. The
+// caller compresses the batch with Compress before splitting it.
+func EncodeWithOptions(data []byte, opts Options) ([]kzg4844.Blob, error) {
+	if !opts.Packing.valid() {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownPacking, opts.Packing)
+	}
+	if !opts.Sequence.valid() {
+		return nil, fmt.Errorf("%w: part %d of %d", ErrBadSequence, opts.Sequence.Index, opts.Sequence.Total)
+	}
+	codec := opts.Codec
+	if opts.Sequence.Total == 0 {
+		var err error
+		if data, codec, err = Compress(data, opts.Codec, opts.Packing); err != nil {
+			return nil, err
+		}
+	} else if !codec.valid() {
+		return nil, fmt.Errorf("%w: sequence parts need a concrete codec, got %v", ErrUnknownCodec, codec)
+	}
+	count := BlobCount(len(data), opts.Packing)
+	if count > MaxBlobs || uint64(len(data)) > math.MaxUint32 {
+		return nil, ErrPayloadTooLarge
+	}
+
+	header := Header{
+		Version:  Version1,
+		Codec:    codec,
+		Packing:  opts.Packing,
+		Count:    uint8(count),
+		Length:   uint32(len(data)),
+		Checksum: Checksum(data),
+		Sequence: opts.Sequence,
+	}
+
+	blobs := make([]kzg4844.Blob, count)
+	for i := range blobs {
+		header.Index = uint8(i)
+		framing := header.marshal()
+		packFieldElements(&blobs[i], 0, framing[:])
+
+		start := i * opts.Packing.Capacity()
+		end := start + opts.Packing.Capacity()
+		if end > len(data) {
+			end = len(data)
+		}
+		opts.Packing.pack(&blobs[i], data[start:end])
+	}
+	return blobs, nil
+}
@@ -0,0 +1,38 @@
+package encoder
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrPayloadTooLarge    = errors.New("payload does not fit in the maximum number of blobs")
+	ErrNoBlobs            = errors.New("no blobs to decode")
+	ErrNonCanonical       = errors.New("field element is not a canonical BLS12-381 scalar")
+	ErrBadMagic           = errors.New("blob does not start with the framing magic")
+	ErrUnsupportedVersion = errors.New("unsupported framing version")
+	ErrUnknownCodec       = errors.New("unknown codec")
+	ErrUnknownPacking     = errors.New("unknown packing mode")
+	ErrBadSequence        = errors.New("invalid sequence metadata")
+	ErrCorrupt            = errors.New("blob contents do not match the framing")
+	ErrTruncated          = errors.New("payload is missing blobs")
+	ErrExtraBlobs         = errors.New("more blobs than the header describes")
+	ErrBlobOrder          = errors.New("blob is out of order")
+	ErrHeaderMismatch     = errors.New("blob header differs from the first blob")
+	ErrChecksum           = errors.New("payload checksum mismatch")
+	ErrDecompress         = errors.New("failed to decompress payload")
+)
+
+// BlobError reports a failure tied to a specific blob of a payload.
+type BlobError struct {
+	Index int
+	Err   error
+}
+
+func (e *BlobError) Error() string {
+	return fmt.Sprintf("blob %d: %v", e.Index, e.Err)
+}
+
+func (e *BlobError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,157 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Magic marks the first field element of every blob produced by this package.
+var Magic = [4]byte{'B', 'L', 'O', 'B'}
+
+// Version1 is the only framing version understood by this package.
+const Version1 = 1
+
+// HeaderSize is the number of header bytes stored in the first field element
+// of every blob.
+const HeaderSize = bytesPerFieldElement
+
+// Codec identifies how the payload bytes were transformed before packing.
+type Codec uint8
+
+const (
+	CodecNone    Codec = 0
+	CodecZstd    Codec = 1
+	CodecS2      Codec = 2
+	CodecDeflate Codec = 3
+
+	// CodecAuto is never written to a header. As an option it makes the
+	// encoder pick whichever codec needs the fewest blobs.
+	CodecAuto Codec = 0xff
+)
+
+func (c Codec) String() string {
+	switch c {
+	case CodecNone:
+		return "none"
+	case CodecZstd:
+		return "zstd"
+	case CodecS2:
+		return "s2"
+	case CodecDeflate:
+		return "deflate"
+	case CodecAuto:
+		return "auto"
+	}
+	return fmt.Sprintf("codec(%d)", uint8(c))
+}
+
+// Header is the framing written at the start of every blob. All blobs of a
+// payload carry the same header apart from Index.
+//
+// Layout (31 bytes, big-endian):
+//
+//	0..3    magic "BLOB"
+//	4       version
+//	5       codec id
+//	6       payload packing
+//	7       blob index
+//	8       blob count
+//	9..12   payload length
+//	13..16  CRC-32C of the payload
+//	17..24  sequence batch id
+//	25..26  sequence index
+//	27..28  sequence total
+//	29..30  reserved, must be zero
+type Header struct {
+	Version  uint8
+	Codec    Codec
+	Packing  Packing
+	Index    uint8
+	Count    uint8
+	Length   uint32
+	Checksum uint32
+	Sequence Sequence
+}
+
+// Sequence links payloads that were split across several transactions. A zero
+// Sequence means the payload stands alone.
+type Sequence struct {
+	Batch uint64 // identifies the original payload, shared by every part
+	Index uint16 // position of this part within the batch
+	Total uint16 // number of parts in the batch
+}
+
+func (s Sequence) valid() bool {
+	if s.Total == 0 {
+		return s == Sequence{}
+	}
+	return s.Index < s.Total
+}
+
+func (h Header) marshal() [HeaderSize]byte {
+	var b [HeaderSize]byte
+	copy(b[0:4], Magic[:])
+	b[4] = h.Version
+	b[5] = byte(h.Codec)
+	b[6] = byte(h.Packing)
+	b[7] = h.Index
+	b[8] = h.Count
+	binary.BigEndian.PutUint32(b[9:13], h.Length)
+	binary.BigEndian.PutUint32(b[13:17], h.Checksum)
+	binary.BigEndian.PutUint64(b[17:25], h.Sequence.Batch)
+	binary.BigEndian.PutUint16(b[25:27], h.Sequence.Index)
+	binary.BigEndian.PutUint16(b[27:29], h.Sequence.Total)
+	return b
+}
+
+func parseHeader(b []byte) (Header, error) {
+	if !bytes.Equal(b[0:4], Magic[:]) {
+		return Header{}, ErrBadMagic
+	}
+	h := Header{
+		Version:  b[4],
+		Codec:    Codec(b[5]),
+		Packing:  Packing(b[6]),
+		Index:    b[7],
+		Count:    b[8],
+		Length:   binary.BigEndian.Uint32(b[9:13]),
+		Checksum: binary.BigEndian.Uint32(b[13:17]),
+		Sequence: Sequence{
+			Batch: binary.BigEndian.Uint64(b[17:25]),
+			Index: binary.BigEndian.Uint16(b[25:27]),
+			Total: binary.BigEndian.Uint16(b[27:29]),
+		},
+	}
+	if h.Version != Version1 {
+		return Header{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, h.Version)
+	}
+	if !h.Codec.valid() {
+		return Header{}, fmt.Errorf("%w: %v", ErrUnknownCodec, h.Codec)
+	}
+	if !h.Packing.valid() {
+		return Header{}, fmt.Errorf("%w: %v", ErrUnknownPacking, h.Packing)
+	}
+	if !h.Sequence.valid() {
+		return Header{}, fmt.Errorf("%w: sequence part %d of %d", ErrCorrupt, h.Sequence.Index, h.Sequence.Total)
+	}
+	if !allZero(b[29:]) {
+		return Header{}, fmt.Errorf("%w: reserved header bytes are set", ErrCorrupt)
+	}
+	if h.Count == 0 || h.Index >= h.Count {
+		return Header{}, fmt.Errorf("%w: blob %d of %d", ErrCorrupt, h.Index, h.Count)
+	}
+	if int(h.Count) != BlobCount(int(h.Length), h.Packing) {
+		return Header{}, fmt.Errorf("%w: %d bytes cannot span %d blobs", ErrCorrupt, h.Length, h.Count)
+	}
+	return h, nil
+}
+
+func allZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
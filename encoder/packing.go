@@ -0,0 +1,165 @@
+package encoder
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// Packing selects how payload bytes are laid out in the field elements that
+// follow the header. The header itself is always packed with Packing31 so the
+// decoder can read the mode before touching the payload.
+type Packing uint8
+
+const (
+	// Packing31 stores 31 bytes per field element and leaves the high byte
+	// zero.
+	Packing31 Packing = 0
+	// Packing254 stores 254 bits per field element and leaves the two high
+	// bits zero. Any 254-bit value is below the BLS12-381 modulus.
+	Packing254 Packing = 1
+)
+
+const bitsPerFieldElement = 254
+
+func (p Packing) String() string {
+	switch p {
+	case Packing31:
+		return "31"
+	case Packing254:
+		return "254"
+	}
+	return fmt.Sprintf("packing(%d)", uint8(p))
+}
+
+// ParsePacking maps the names printed by Packing.String back to a mode.
+func ParsePacking(name string) (Packing, error) {
+	switch name {
+	case "31":
+		return Packing31, nil
+	case "254":
+		return Packing254, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownPacking, name)
+}
+
+// Capacity returns the number of payload bytes a single blob holds.
+func (p Packing) Capacity() int {
+	if p == Packing254 {
+		return (fieldElementsPerBlob - 1) * bitsPerFieldElement / 8
+	}
+	return (fieldElementsPerBlob - 1) * bytesPerFieldElement
+}
+
+func (p Packing) valid() bool {
+	return p == Packing31 || p == Packing254
+}
+
+func (p Packing) pack(blob *kzg4844.Blob, data []byte) {
+	if p == Packing254 {
+		packBits(blob, 1, data)
+		return
+	}
+	packFieldElements(blob, 1, data)
+}
+
+func (p Packing) unpack(blob *kzg4844.Blob, n int) ([]byte, error) {
+	if p == Packing254 {
+		return unpackBits(blob, 1, n)
+	}
+	return unpackFieldElements(blob, 1, n)
+}
+
+// packFieldElements writes data 31 bytes at a time into consecutive field
+// elements of blob, starting at field element first.
+func packFieldElements(blob *kzg4844.Blob, first int, data []byte) {
+	for i, fe := 0, first; i < len(data); i, fe = i+bytesPerFieldElement, fe+1 {
+		end := i + bytesPerFieldElement
+		if end > len(data) {
+			end = len(data)
+		}
+		copy(blob[fe*fieldElementSize+1:], data[i:end])
+	}
+}
+
+// unpackFieldElements reads n payload bytes starting at field element first.
+// Everything outside the payload bytes, including the unused tail of the
+// blob, must be zero.
+func unpackFieldElements(blob *kzg4844.Blob, first int, n int) ([]byte, error) {
+	data := make([]byte, 0, n)
+	for fe := first; fe < fieldElementsPerBlob; fe++ {
+		offset := fe * fieldElementSize
+		if blob[offset] != 0 {
+			return nil, fmt.Errorf("%w: field element %d has its high byte set", ErrCorrupt, fe)
+		}
+		take := n - len(data)
+		if take > bytesPerFieldElement {
+			take = bytesPerFieldElement
+		}
+		data = append(data, blob[offset+1:offset+1+take]...)
+		if !allZero(blob[offset+1+take : offset+fieldElementSize]) {
+			return nil, fmt.Errorf("%w: field element %d has data past the payload", ErrCorrupt, fe)
+		}
+	}
+	return data, nil
+}
+
+// packBits treats data as a big-endian bit stream and stores 254 bits of it,
+// right-aligned, in each field element starting at field element first.
+func packBits(blob *kzg4844.Blob, first int, data []byte) {
+	totalBits := len(data) * 8
+	for bit, fe := 0, first; bit < totalBits; bit, fe = bit+bitsPerFieldElement, fe+1 {
+		// The element's bits sit inside a 33-byte window of data. Shifting
+		// the window right by shift bits right-aligns them in its last 32
+		// bytes.
+		var window [fieldElementSize + 1]byte
+		copy(window[:], data[bit/8:])
+		shift := len(window)*8 - bit%8 - bitsPerFieldElement
+		q, r := shift/8, uint(shift%8)
+
+		dst := blob[fe*fieldElementSize : (fe+1)*fieldElementSize]
+		for i := range dst {
+			dst[i] = byteAt(window[:], i+1-q)>>r | byteAt(window[:], i-q)<<(8-r)
+		}
+		dst[0] &= 0x3f
+	}
+}
+
+// unpackBits reverses packBits for a payload of n bytes. The two high bits of
+// every element and all bits past the payload must be zero.
+func unpackBits(blob *kzg4844.Blob, first int, n int) ([]byte, error) {
+	// Leave room for the last window so writes never need bounds checks.
+	out := make([]byte, n+fieldElementSize+1)
+	for bit, fe := 0, first; fe < fieldElementsPerBlob; bit, fe = bit+bitsPerFieldElement, fe+1 {
+		src := blob[fe*fieldElementSize : (fe+1)*fieldElementSize]
+		if src[0]&0xc0 != 0 {
+			return nil, fmt.Errorf("%w: field element %d has its high bits set", ErrCorrupt, fe)
+		}
+		if bit >= n*8 {
+			if !allZero(src) {
+				return nil, fmt.Errorf("%w: field element %d has data past the payload", ErrCorrupt, fe)
+			}
+			continue
+		}
+		var element [fieldElementSize + 1]byte
+		copy(element[1:], src)
+		shift := len(element)*8 - bit%8 - bitsPerFieldElement
+		q, r := shift/8, uint(shift%8)
+
+		dst := out[bit/8:]
+		for i := range element {
+			dst[i] |= byteAt(element[:], i+q)<<r | byteAt(element[:], i+q+1)>>(8-r)
+		}
+	}
+	if !allZero(out[n:]) {
+		return nil, fmt.Errorf("%w: data past the payload", ErrCorrupt)
+	}
+	return out[:n], nil
+}
+
+func byteAt(b []byte, i int) byte {
+	if i < 0 || i >= len(b) {
+		return 0
+	}
+	return b[i]
+}
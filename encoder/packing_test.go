@@ -0,0 +1,119 @@
+package encoder
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// referencePackBits packs data one bit at a time: bit i of the stream, most
+// significant first, becomes bit 2+i%254 (counting from the most significant
+// bit) of field element first+i/254.
+func referencePackBits(first int, data []byte) kzg4844.Blob {
+	var blob kzg4844.Blob
+	for i := 0; i < len(data)*8; i++ {
+		if data[i/8]>>(7-i%8)&1 == 0 {
+			continue
+		}
+		pos := (first+i/bitsPerFieldElement)*fieldElementSize*8 + 2 + i%bitsPerFieldElement
+		blob[pos/8] |= 1 << (7 - pos%8)
+	}
+	return blob
+}
+
+func TestPackBits(t *testing.T) {
+	capacity := Packing254.Capacity()
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"one byte", []byte{0xa5}},
+		{"one element", bytes.Repeat([]byte{0xff}, bitsPerFieldElement/8)},
+		{"one bit over an element", bytes.Repeat([]byte{0xff}, 32)},
+		{"two elements", testPayload(2 * bitsPerFieldElement / 8)},
+		{"four elements end on a byte", testPayload(4 * bitsPerFieldElement / 8)},
+		{"alternating bits", bytes.Repeat([]byte{0x55}, 200)},
+		{"random", testPayload(1001)},
+		{"full blob", testPayload(capacity)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var blob kzg4844.Blob
+			packBits(&blob, 1, tt.data)
+			if want := referencePackBits(1, tt.data); blob != want {
+				t.Fatal("packed blob differs from bit-by-bit packing")
+			}
+			for fe := 0; fe < fieldElementsPerBlob; fe++ {
+				if blob[fe*fieldElementSize]&0xc0 != 0 {
+					t.Fatalf("field element %d has its high bits set", fe)
+				}
+			}
+			got, err := unpackBits(&blob, 1, len(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Fatal("unpacked data differs")
+			}
+		})
+	}
+}
+
+func TestPackBitsElementBoundary(t *testing.T) {
+	// 256 set bits fill the first element and spill two into the second.
+	var blob kzg4844.Blob
+	packBits(&blob, 1, bytes.Repeat([]byte{0xff}, 32))
+
+	first := blob[fieldElementSize : 2*fieldElementSize]
+	if first[0] != 0x3f || !bytes.Equal(first[1:], bytes.Repeat([]byte{0xff}, fieldElementSize-1)) {
+		t.Fatalf("first element %x", first)
+	}
+	second := blob[2*fieldElementSize : 3*fieldElementSize]
+	if second[0] != 0x30 || !allZero(second[1:]) {
+		t.Fatalf("second element %x", second)
+	}
+}
+
+func TestPackingCapacity(t *testing.T) {
+	if got, want := Packing254.Capacity(), (fieldElementsPerBlob-1)*254/8; got != want {
+		t.Fatalf("254-bit capacity %d, want %d", got, want)
+	}
+	if Packing254.Capacity() <= Packing31.Capacity() {
+		t.Fatal("254-bit packing holds no more than 31-byte packing")
+	}
+	for _, packing := range []Packing{Packing31, Packing254} {
+		blobs, err := EncodeWithOptions(testPayload(packing.Capacity()), Options{Packing: packing})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blobs) != 1 {
+			t.Fatalf("%v: a full payload takes %d blobs", packing, len(blobs))
+		}
+	}
+}
+
+func TestUnpackBitsCorrupt(t *testing.T) {
+	data := testPayload(100) // fills field elements 1 to 3 and 38 bits of 4
+	tests := []struct {
+		name    string
+		corrupt func(*kzg4844.Blob)
+	}{
+		{"high bit set", func(b *kzg4844.Blob) { b[2*fieldElementSize] |= 0x80 }},
+		{"second high bit set", func(b *kzg4844.Blob) { b[fieldElementSize] |= 0x40 }},
+		{"bit past the payload in the last element", func(b *kzg4844.Blob) { b[5*fieldElementSize-1] |= 1 }},
+		{"bit in an unused element", func(b *kzg4844.Blob) { b[10*fieldElementSize+5] = 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var blob kzg4844.Blob
+			packBits(&blob, 1, data)
+			tt.corrupt(&blob)
+			if _, err := unpackBits(&blob, 1, len(data)); !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("got %v, want %v", err, ErrCorrupt)
+			}
+		})
+	}
+}
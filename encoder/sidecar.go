@@ -0,0 +1,68 @@
+package encoder
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"golang.org/x/sync/errgroup"
+)
+
+// Sidecar encodes data into blobs and computes the matching commitments and
+// proofs.
+func Sidecar(data []byte, opts Options) (*types.BlobTxSidecar, error) {
+	blobs, err := EncodeWithOptions(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewSidecar(blobs)
+}
+
+// NewSidecar computes the commitment and proof for every blob, using one
+// worker per CPU.
+func NewSidecar(blobs []kzg4844.Blob) (*types.BlobTxSidecar, error) {
+	return BuildSidecar(context.Background(), blobs, 0)
+}
+
+// BuildSidecar computes the commitment and proof for every blob on at most
+// workers goroutines, or one per CPU if workers is not positive. Commitments
+// and proofs are in the same order as blobs regardless of scheduling. The
+// first failure, or cancellation of ctx, stops the remaining work.
+func BuildSidecar(ctx context.Context, blobs []kzg4844.Blob, workers int) (*types.BlobTxSidecar, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       blobs,
+		Commitments: make([]kzg4844.Commitment, len(blobs)),
+		Proofs:      make([]kzg4844.Proof, len(blobs)),
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+	for i := range blobs {
+		i := i
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			commit, err := kzg4844.BlobToCommitment(blobs[i])
+			if err != nil {
+				return &BlobError{Index: i, Err: fmt.Errorf("failed to compute commitment: %w", err)}
+			}
+			proof, err := kzg4844.ComputeBlobProof(blobs[i], commit)
+			if err != nil {
+				return &BlobError{Index: i, Err: fmt.Errorf("failed to compute proof: %w", err)}
+			}
+			sidecar.Commitments[i] = commit
+			sidecar.Proofs[i] = proof
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return sidecar, nil
+}
@@ -0,0 +1,114 @@
+package encoder
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+// The C backend is only available with cgo and the ckzg tag
+// (go test -mod=mod -tags ckzg -bench BuildSidecar ./encoder), since the
+// vendored bindings lack the C sources.
+
+func BenchmarkBuildSidecarGoKZG(b *testing.B) { benchmarkBuildSidecar(b, false) }
+func BenchmarkBuildSidecarCKZG(b *testing.B)  { benchmarkBuildSidecar(b, true) }
+
+// benchmarkBuildSidecar times sidecar construction for one to six blobs,
+// sequentially and with a worker per CPU.
+func benchmarkBuildSidecar(b *testing.B, ckzg bool) {
+	if err := kzg4844.UseCKZG(ckzg); err != nil {
+		b.Skip(err)
+	}
+	defer kzg4844.UseCKZG(false)
+
+	workerCounts := []int{1}
+	if n := runtime.GOMAXPROCS(0); n > 1 {
+		workerCounts = append(workerCounts, n)
+	}
+	for _, count := range []int{1, 2, 6} {
+		blobs := randomBlobs(b, count)
+		for _, workers := range workerCounts {
+			b.Run(fmt.Sprintf("blobs=%d/workers=%d", count, workers), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := BuildSidecar(context.Background(), blobs, workers); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*count), "ns/blob")
+			})
+		}
+	}
+}
+
+// randomBlobs encodes enough random bytes to fill count blobs.
+func randomBlobs(tb testing.TB, count int) []kzg4844.Blob {
+	data := make([]byte, count*Packing31.Capacity())
+	if _, err := rand.Read(data); err != nil {
+		tb.Fatal(err)
+	}
+	blobs, err := Encode(data)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return blobs
+}
+
+func TestBuildSidecarSameForAnyWorkerCount(t *testing.T) {
+	blobs := randomBlobs(t, 4)
+	want, err := BuildSidecar(context.Background(), blobs, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range blobs {
+		if err := kzg4844.VerifyBlobProof(blobs[i], want.Commitments[i], want.Proofs[i]); err != nil {
+			t.Fatalf("blob %d: %v", i, err)
+		}
+	}
+	for _, workers := range []int{2, 3, 8, 0} {
+		got, err := BuildSidecar(context.Background(), blobs, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range blobs {
+			if got.Commitments[i] != want.Commitments[i] || got.Proofs[i] != want.Proofs[i] {
+				t.Fatalf("blob %d differs with %d workers", i, workers)
+			}
+		}
+	}
+}
+
+func TestBuildSidecarCancel(t *testing.T) {
+	blobs := randomBlobs(t, 6)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BuildSidecar(ctx, blobs, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v from a cancelled build, want %v", err, context.Canceled)
+	}
+
+	start := time.Now()
+	if _, err := BuildSidecar(context.Background(), blobs[:1], 1); err != nil {
+		t.Fatal(err)
+	}
+	perBlob := time.Since(start)
+
+	// Cancel while the first blob is being worked on: the remaining five must
+	// not be started.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(perBlob/2, cancel)
+	start = time.Now()
+	sidecar, err := BuildSidecar(ctx, blobs, 1)
+	if !errors.Is(err, context.Canceled) || sidecar != nil {
+		t.Fatalf("got sidecar %v and error %v, want %v", sidecar, err, context.Canceled)
+	}
+	if took := time.Since(start); took > 4*perBlob {
+		t.Fatalf("cancelled build took %v, a blob takes %v", took, perBlob)
+	}
+}
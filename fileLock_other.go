@@ -0,0 +1,36 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// lockFile claims path by creating it exclusively and releases it by removing
+// it again. Unlike flock the claim outlives a crashed process; the file then
+// has to be removed by hand.
+func lockFile(path string) (io.Closer, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%s is held by another process; remove it if none is running", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return exclusiveFile{file}, nil
+}
+
+type exclusiveFile struct {
+	*os.File
+}
+
+func (f exclusiveFile) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"blob/rollup"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type scanConfig struct {
+	InitialRange  uint64 // blocks in the first request
+	MaxRange      uint64 // the range doubles after every successful request up to this
+	Confirmations uint64 // blocks behind the head the scan stops at, so reorgs cannot rewrite it
+}
+
+func defaultScanConfig() scanConfig {
+	return scanConfig{
+		InitialRange:  2000,
+		MaxRange:      50000,
+		Confirmations: 12,
+	}
+}
+
+type resultRecord struct {
+	Solver common.Address `json:"solver"`
+	Root   common.Hash    `json:"root"`
+	Block  uint64         `json:"block"`
+	TxHash common.Hash    `json:"txHash"`
+}
+
+type disputeRecord struct {
+	Disputer common.Address `json:"disputer"`
+	Block    uint64         `json:"block"`
+	TxHash   common.Hash    `json:"txHash"`
+}
+
+// requestRecord is everything the contract logged about one request. Every
+// dispute the contract accepts has found the submitted root wrong.
+type requestRecord struct {
+	RequestId *big.Int        `json:"requestId"`
+	Sender    common.Address  `json:"sender"`
+	Block     uint64          `json:"block"`
+	TxHash    common.Hash     `json:"txHash"`
+	Results   []resultRecord  `json:"results"`
+	Disputes  []disputeRecord `json:"disputes"`
+}
+
+// scanProgress is the state a scan persists after every range, so an
+// interrupted scan resumes where it stopped with the same range size.
+type scanProgress struct {
+	Contract   common.Address   `json:"contract"`
+	Deployment uint64           `json:"deployment"`
+	Scanned    uint64           `json:"scanned"` // last block fully scanned
+	Range      uint64           `json:"range"`
+	Requests   []*requestRecord `json:"requests"`
+}
+
+// logScanner rebuilds the history of a Rollup contract from its logs,
+// walking from the deployment block in ranges small enough for the provider
+// to answer.
+type logScanner struct {
+	client chainClient
+	path   string
+	cfg    scanConfig
+
+	progress scanProgress
+	requests map[string]*requestRecord
+}
+
+// openLogScanner resumes the scan saved at path, or starts a new one at
+// deployment. A deployment of 0 is looked up from the contract code.
+func openLogScanner(ctx context.Context, client chainClient, contract common.Address, deployment uint64, path string, cfg scanConfig) (*logScanner, error) {
+	s := &logScanner{client: client, path: path, cfg: cfg, requests: make(map[string]*requestRecord)}
+
+	raw, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(raw, &s.progress); err != nil {
+			return nil, fmt.Errorf("failed to decode scan progress: %v", err)
+		}
+		if s.progress.Contract != contract {
+			return nil, fmt.Errorf("scan progress in %s is for contract %s, not %s", path, s.progress.Contract.Hex(), contract.Hex())
+		}
+		for _, r := range s.progress.Requests {
+			s.requests[r.RequestId.String()] = r
+		}
+		return s, nil
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read scan progress: %v", err)
+	}
+
+	if deployment == 0 {
+		if deployment, err = findDeployment(ctx, client, contract); err != nil {
+			return nil, err
+		}
+		log.Printf("Contract %s deployed in block %d", contract.Hex(), deployment)
+	}
+	s.progress = scanProgress{Contract: contract, Deployment: deployment, Scanned: deployment - 1, Range: cfg.InitialRange}
+	return s, nil
+}
+
+// findDeployment binary searches for the first block in which contract has
+// code. It needs a node that serves historical state.
+func findDeployment(ctx context.Context, client chainClient, contract common.Address) (uint64, error) {
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch head block number: %v", err)
+	}
+	hasCode := func(block uint64) (bool, error) {
+		code, err := client.CodeAt(ctx, contract, new(big.Int).SetUint64(block))
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch code of %s at block %d: %v", contract.Hex(), block, err)
+		}
+		return len(code) > 0, nil
+	}
+	if ok, err := hasCode(head); err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("no contract deployed at %s", contract.Hex())
+		}
+		return 0, err
+	}
+	lo, hi := uint64(0), head
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ok, err := hasCode(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if lo == 0 {
+		return 1, nil // genesis allocation; logs start with block 1
+	}
+	return lo, nil
+}
+
+// Scan walks from the saved progress up to the confirmed head. The range
+// halves whenever the provider refuses a request as too large and doubles
+// after each one it answers.
+func (s *logScanner) Scan(ctx context.Context) error {
+	head, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch head block number: %v", err)
+	}
+	if head < s.cfg.Confirmations {
+		return nil
+	}
+	target := head - s.cfg.Confirmations
+
+	for s.progress.Scanned < target {
+		from := s.progress.Scanned + 1
+		to := from + s.progress.Range - 1
+		if to > target {
+			to = target
+		}
+		logs, err := s.client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{s.progress.Contract},
+		})
+		if err != nil {
+			if isRangeTooLarge(err) && s.progress.Range > 1 {
+				s.progress.Range /= 2
+				log.Printf("Blocks %d-%d return too many logs, retrying with %d blocks", from, to, s.progress.Range)
+				continue
+			}
+			return fmt.Errorf("failed to fetch logs in blocks %d-%d: %v", from, to, err)
+		}
+
+		for _, l := range logs {
+			s.apply(l)
+		}
+		s.progress.Scanned = to
+		if s.progress.Range *= 2; s.progress.Range > s.cfg.MaxRange {
+			s.progress.Range = s.cfg.MaxRange
+		}
+		if err := s.save(); err != nil {
+			return err
+		}
+		log.Printf("Scanned blocks %d-%d: %d logs, %d blocks left", from, to, len(logs), target-to)
+	}
+	return nil
+}
+
+// apply records the receipt, result or dispute l carries. Other events do
+// not belong to a request and are skipped.
+func (s *logScanner) apply(l types.Log) {
+	ev, err := rollup.DecodeEvent(l)
+	if err != nil {
+		log.Printf("Skipping log %d of block %d: %v", l.Index, l.BlockNumber, err)
+		return
+	}
+	switch ev := ev.(type) {
+	case *rollup.NewReceipt:
+		r := s.request(ev.RequestId)
+		r.Sender, r.Block, r.TxHash = ev.Sender, ev.Block, ev.TxHash
+	case *rollup.ResultSubmitted:
+		r := s.request(ev.RequestId)
+		r.Results = append(r.Results, resultRecord{Solver: ev.Solver, Root: ev.ResultRoot, Block: ev.Block, TxHash: ev.TxHash})
+	case *rollup.DisputeRaised:
+		r := s.request(ev.RequestId)
+		r.Disputes = append(r.Disputes, disputeRecord{Disputer: ev.Disputer, Block: ev.Block, TxHash: ev.TxHash})
+	}
+}
+
+func (s *logScanner) request(id *big.Int) *requestRecord {
+	r, ok := s.requests[id.String()]
+	if !ok {
+		r = &requestRecord{RequestId: id}
+		s.requests[id.String()] = r
+		s.progress.Requests = append(s.progress.Requests, r)
+	}
+	return r
+}
+
+// Requests returns every request seen so far, ordered by id.
+func (s *logScanner) Requests() []*requestRecord {
+	requests := append([]*requestRecord(nil), s.progress.Requests...)
+	sort.Slice(requests, func(i, j int) bool { return requests[i].RequestId.Cmp(requests[j].RequestId) < 0 })
+	return requests
+}
+
+func (s *logScanner) save() error {
+	raw, err := json.Marshal(s.progress)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, raw); err != nil {
+		return fmt.Errorf("failed to save scan progress: %v", err)
+	}
+	return nil
+}
+
+// isRangeTooLarge reports whether a provider refused a log query for
+// covering too many blocks or matching too many logs. Providers word this
+// differently; these are the messages of geth, Infura, Alchemy, QuickNode
+// and Ankr. Generic phrases such as "limit exceeded" are left out on purpose:
+// providers use them for rate limits too, which a smaller range does not fix.
+func isRangeTooLarge(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"query returned more than",    // geth, Infura
+		"log response size exceeded",  // Alchemy
+		"eth_getlogs is limited to a", // QuickNode
+		"exceed maximum block range",  // geth, Ankr
+		"block range is too wide",     // Ankr
+		"block range is too large",    // Alchemy
+		"query timeout exceeded",      // Alchemy, when a range matches too much
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func printRequests(requests []*requestRecord) {
+	fmt.Printf("%-8s %-42s %9s %8s %9s\n", "request", "sender", "block", "results", "disputes")
+	for _, r := range requests {
+		fmt.Printf("%-8v %-42s %9d %8d %9d\n", r.RequestId, r.Sender.Hex(), r.Block, len(r.Results), len(r.Disputes))
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// rangeLimitedChain refuses log queries over more than maxRange blocks the
+// way geth does, and records every query it gets.
+type rangeLimitedChain struct {
+	logChain
+	maxRange   uint64
+	deployment uint64 // first block with contract code
+	failAt     int    // query to fail with a transport error, counting from 1
+	queries    [][2]uint64
+	codeCalls  int
+}
+
+func (c *rangeLimitedChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+	c.queries = append(c.queries, [2]uint64{from, to})
+	if len(c.queries) == c.failAt {
+		return nil, errors.New("connection refused")
+	}
+	if c.maxRange > 0 && to-from+1 > c.maxRange {
+		return nil, errors.New("query returned more than 10000 results")
+	}
+	return c.logChain.FilterLogs(ctx, q)
+}
+
+func (c *rangeLimitedChain) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	c.codeCalls++
+	if blockNumber.Uint64() < c.deployment {
+		return nil, nil
+	}
+	return []byte{0x60}, nil
+}
+
+func openTestLogScanner(t *testing.T, client chainClient, path string, cfg scanConfig) *logScanner {
+	t.Helper()
+	s, err := openLogScanner(context.Background(), client, common.HexToAddress(contractAddress), 1, path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLogScannerAdaptsRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     uint64
+		maxRange uint64
+		cfg      scanConfig
+		want     [][2]uint64
+	}{
+		{
+			name: "doubles up to the maximum",
+			head: 60,
+			cfg:  scanConfig{InitialRange: 2, MaxRange: 16},
+			want: [][2]uint64{{1, 2}, {3, 6}, {7, 14}, {15, 30}, {31, 46}, {47, 60}},
+		},
+		{
+			name:     "halves when refused",
+			head:     20,
+			maxRange: 5,
+			cfg:      scanConfig{InitialRange: 8, MaxRange: 16},
+			want:     [][2]uint64{{1, 8}, {1, 4}, {5, 12}, {5, 8}, {9, 16}, {9, 12}, {13, 20}, {13, 16}, {17, 20}},
+		},
+		{
+			name: "stops short of the head",
+			head: 20,
+			cfg:  scanConfig{InitialRange: 4, MaxRange: 4, Confirmations: 8},
+			want: [][2]uint64{{1, 4}, {5, 8}, {9, 12}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &rangeLimitedChain{maxRange: tt.maxRange}
+			chain.set(tt.head)
+			s := openTestLogScanner(t, chain, filepath.Join(t.TempDir(), "scan.json"), tt.cfg)
+			if err := s.Scan(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(chain.queries, tt.want) {
+				t.Fatalf("queried %v, want %v", chain.queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogScannerResumes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.json")
+	cfg := scanConfig{InitialRange: 4, MaxRange: 8}
+	chain := &rangeLimitedChain{failAt: 3}
+	chain.set(30, newReceiptLog(3, 0, 1), newReceiptLog(20, 0, 2))
+
+	// Blocks 1-4 and 5-12 are scanned before the connection drops.
+	if err := openTestLogScanner(t, chain, path, cfg).Scan(context.Background()); err == nil {
+		t.Fatal("scan survived a dropped connection")
+	}
+
+	chain.failAt, chain.queries = 0, nil
+	s := openTestLogScanner(t, chain, path, cfg)
+	if err := s.Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][2]uint64{{13, 20}, {21, 28}, {29, 30}}; !reflect.DeepEqual(chain.queries, want) {
+		t.Fatalf("resumed with %v, want %v", chain.queries, want)
+	}
+	requests := s.Requests()
+	if len(requests) != 2 || requests[0].RequestId.Int64() != 1 || requests[0].Block != 3 || requests[1].RequestId.Int64() != 2 {
+		t.Fatalf("found requests %+v", requests)
+	}
+}
+
+func TestLogScannerRateLimited(t *testing.T) {
+	chain := &rangeLimitedChain{}
+	chain.set(100)
+	rateLimited := &rateLimitedChain{rangeLimitedChain: chain}
+	s := openTestLogScanner(t, rateLimited, filepath.Join(t.TempDir(), "scan.json"), scanConfig{InitialRange: 8, MaxRange: 8})
+	if err := s.Scan(context.Background()); err == nil {
+		t.Fatal("scan went on while rate limited")
+	}
+	if s.progress.Range != 8 {
+		t.Fatalf("range shrank to %d on a rate limit", s.progress.Range)
+	}
+}
+
+// rateLimitedChain refuses every log query with Infura's rate limit error.
+type rateLimitedChain struct {
+	*rangeLimitedChain
+}
+
+func (c *rateLimitedChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return nil, errors.New("daily request count exceeded, request rate limited")
+}
+
+func TestIsRangeTooLarge(t *testing.T) {
+	tests := map[string]bool{
+		"query returned more than 10000 results":                                                    true,
+		"Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range": true,
+		"eth_getLogs is limited to a 10,000 range":                                                  true,
+		"exceed maximum block range: 50000":                                                         true,
+		"block range is too wide":                                                                   true,
+		"limit exceeded":                                                                            false,
+		"daily request count exceeded, request rate limited":                                        false,
+		"429 Too Many Requests: {\"jsonrpc\":\"2.0\",\"error\":{\"code\":-32005}}":                  false,
+		"Your app has exceeded its compute units per second capacity":                               false,
+		"invalid block range params":                                                                false,
+	}
+	for msg, want := range tests {
+		if got := isRangeTooLarge(errors.New(msg)); got != want {
+			t.Errorf("isRangeTooLarge(%q) = %v, want %v", msg, got, want)
+		}
+	}
+}
+
+func TestFindDeployment(t *testing.T) {
+	tests := []struct {
+		deployment uint64
+		want       uint64
+	}{
+		{37, 37},
+		{100, 100},
+		{1, 1},
+		{0, 1}, // genesis allocation
+	}
+	for _, tt := range tests {
+		chain := &rangeLimitedChain{deployment: tt.deployment}
+		chain.set(100)
+		got, err := findDeployment(context.Background(), chain, common.HexToAddress(contractAddress))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("deployment at %d found at %d, want %d", tt.deployment, got, tt.want)
+		}
+		// One call for the head, then a binary search over 101 blocks.
+		if chain.codeCalls > 1+7 {
+			t.Errorf("deployment at %d took %d code lookups", tt.deployment, chain.codeCalls)
+		}
+	}
+
+	chain := &rangeLimitedChain{deployment: 101}
+	chain.set(100)
+	if _, err := findDeployment(context.Background(), chain, common.HexToAddress(contractAddress)); err == nil {
+		t.Fatal("found a contract that is not deployed")
+	}
+}
@@ -4,74 +4,496 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"math/big"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"blob/beacon"
+	"blob/encoder"
+	"blob/signer"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/holiman/uint256"
 	"github.com/joho/godotenv"
+	"golang.org/x/term"
 )
 
 const TO_ADDRESS = "0xF5106D4ef61cd0a04a345495f59f536bB7cd6074"
 
 func main() {
+	packingFlag := flag.String("packing", encoder.Packing31.String(), "blob payload packing: 31 (bytes per field element) or 254 (bits per field element)")
+	codecFlag := flag.String("codec", encoder.CodecNone.String(), "blob payload compression: none, zstd, s2, deflate or auto (fewest blobs)")
+	layoutFlag := flag.String("layout", "framed", "blob layout: framed (encoded payload) or cells (one matrix cell per field element)")
+	cellFlag := flag.Int("cell", 0, "matrix cell index opened by prove-cell")
+	archiveFlag := flag.String("archive", "blob-archive", "directory of the local blob archive")
+	blobFeeFlag := flag.String("blob-fee", "projected", "blob fee cap strategy: projected (from excess blob gas) or fixed")
+	blobFeeBlocksFlag := flag.Uint64("blob-fee-blocks", 3, "blocks ahead the projected blob fee must cover")
+	blobFeeMultiplierFlag := flag.Float64("blob-fee-multiplier", 1.25, "safety multiplier applied to the projected blob fee")
+	blobFeeCapFlag := flag.Uint64("blob-fee-cap", 3e10, "blob fee cap in wei for the fixed strategy")
+	stuckAfterFlag := flag.Uint64("stuck-after", defaultReplacementConfig().StuckAfter, "blocks a transaction may stay pending before it is replaced with bumped fees")
+	maxSpendFlag := flag.String("max-spend", defaultReplacementConfig().MaxSpend.String(), "highest worst-case cost in wei a replacement may commit to")
+	journalFlag := flag.String("journal", "tx-journal.jsonl", "append-only journal of every signed transaction, replayed on startup")
+	nonceFileFlag := flag.String("nonce-file", "nonce-state.json", "file the nonce manager persists its state to")
+	confirmationsFlag := flag.Uint64("confirmations", defaultTrackerConfig().Confirmations, "blocks to wait for before a submission counts as landed")
+	formatFlag := flag.String("format", "json", "file format written by build and sign: json or rlp")
+	chainIdFlag := flag.Uint64("chain-id", 0, "chain id of transactions made by build")
+	nonceFlag := flag.Uint64("nonce", 0, "first nonce of transactions made by build")
+	tipFlag := flag.String("tip", "", "priority fee in wei of transactions made by build")
+	maxFeeFlag := flag.String("max-fee", "", "fee cap in wei of transactions made by build")
+	gasFlag := flag.Uint64("gas", 2500000, "gas limit of transactions made by build")
+	signerFlag := flag.String("signer", "key", "signer backend: key (PRIVATE_KEY), keystore (KEYSTORE_FILE, passphrase prompted for unless KEYSTORE_PASSPHRASE is set) or remote (SIGNER_URL and SIGNER_ADDRESS)")
+	execGasFlag := flag.Uint64("exec-gas", 150000, "gas the submitResult call uses beyond intrinsic and calldata gas, for advise")
+	watchCheckpointFlag := flag.String("watch-checkpoint", "watcher-checkpoint.json", "file the receipt watcher persists its position to")
+	fromBlockFlag := flag.Uint64("from-block", 0, "block watch starts at without a checkpoint; 0 follows new receipts only")
+	scanFileFlag := flag.String("scan-file", "scan-state.json", "file scan saves its progress and the rebuilt contract history to")
+	deployBlockFlag := flag.Uint64("deploy-block", 0, "block the contract was deployed in, where scan starts; 0 looks it up")
+	requestIdFlag := flag.Int64("request-id", 0, "request to submit the result of; required by matrices, submit defaults to the latest")
+	matricesFlag := flag.String("matrices", "matrices.json", "file of the request's matrices build computes the result from, as written by matrices")
+	flag.Parse()
+
+	packing, err := encoder.ParsePacking(*packingFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	codec, err := encoder.ParseCodec(*codecFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *layoutFlag != "framed" && *layoutFlag != "cells" {
+		log.Fatalf("Unknown layout %q", *layoutFlag)
+	}
+
+	switch flag.Arg(0) {
+	case "", "submit", "resume", "fetch", "broadcast", "advise", "rpc-status", "watch", "scan", "events", "matrices":
+	case "build":
+		p, err := offlineTxParams(*chainIdFlag, *tipFlag, *maxFeeFlag, *blobFeeCapFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		blobTxs, err := buildOfflineTxs(p, *nonceFlag, *layoutFlag, encoder.Options{Codec: codec, Packing: packing}, *matricesFlag, *requestIdFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for i := range blobTxs {
+			if blobTxs[i], err = withGas(blobTxs[i], *gasFlag); err != nil {
+				log.Fatal(err)
+			}
+		}
+		out := argOr(1, "blob-txs."+*formatFlag)
+		if err := writeTxFile(out, *formatFlag, blobTxs); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Wrote %d unsigned transactions to %s", len(blobTxs), out)
+		return
+	case "sign":
+		if err := loadEnv(); err != nil {
+			log.Fatal("Error loading .env file:", err)
+		}
+		txSigner, err := openSigner(context.Background(), *signerFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		in := argOr(1, "blob-txs."+*formatFlag)
+		out := argOr(2, filepath.Join(filepath.Dir(in), "signed-"+filepath.Base(in)))
+		err = signTxFile(in, out, txSigner)
+		closeSigner(txSigner)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Wrote signed transactions to %s", out)
+		return
+	case "import-key":
+		if err := loadEnv(); err != nil {
+			log.Fatal("Error loading .env file:", err)
+		}
+		privateKey, err := getECDSAPrivateKey(os.Getenv("PRIVATE_KEY"))
+		if err != nil {
+			log.Fatalf("Error getting private key: %v", err)
+		}
+		out := argOr(1, os.Getenv("KEYSTORE_FILE"))
+		passphrase, err := keystorePassphrase(true)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := signer.WriteKeystore(out, privateKey, passphrase); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Wrote encrypted key of %s to %s", crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), out)
+		return
+	case "prove-cell":
+		if err := printCellProof(resultMatrix(), *cellFlag); err != nil {
+			log.Fatal(err)
+		}
+		return
+	default:
+		log.Fatalf("Unknown command %q", flag.Arg(0))
+	}
+
+	var blobFees blobFeeStrategy
+	switch *blobFeeFlag {
+	case "projected":
+		blobFees = projectedBlobFee{Blocks: *blobFeeBlocksFlag, Multiplier: *blobFeeMultiplierFlag}
+	case "fixed":
+		blobFees = fixedBlobFee{Cap: uint256.NewInt(*blobFeeCapFlag)}
+	default:
+		log.Fatalf("Unknown blob fee strategy %q", *blobFeeFlag)
+	}
+
 	if err := loadEnv(); err != nil {
 		log.Fatal("Error loading .env file:", err)
 	}
 
-	client, err := ethclient.DialContext(context.Background(), os.Getenv("NODE_URL"))
+	client, err := dialRPCPool(context.Background(), strings.Split(os.Getenv("NODE_URL"), ","), defaultRPCPoolConfig())
 	if err != nil {
 		log.Fatalf("Failed to connect to the Ethereum client: %v", err)
 	}
+	defer client.Close()
+
+	if flag.Arg(0) == "rpc-status" {
+		client.logStats()
+		return
+	}
+
+	if flag.Arg(0) == "watch" {
+		if err := watchReceipts(context.Background(), client, *watchCheckpointFlag, *fromBlockFlag); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "events" {
+		from, err := strconv.ParseUint(flag.Arg(1), 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid first block %q", flag.Arg(1))
+		}
+		var to uint64
+		if flag.NArg() > 2 {
+			if to, err = strconv.ParseUint(flag.Arg(2), 10, 64); err != nil {
+				log.Fatalf("Invalid last block %q", flag.Arg(2))
+			}
+		} else if to, err = client.BlockNumber(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		if err := printEvents(context.Background(), client, from, to); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "matrices" {
+		if *requestIdFlag <= 0 {
+			log.Fatal("matrices needs the -request-id to read")
+		}
+		requestId := big.NewInt(*requestIdFlag)
+		if err := checkRequestReceived(context.Background(), client, *scanFileFlag, *deployBlockFlag, requestId); err != nil {
+			log.Fatal(err)
+		}
+		matrices, err := GetMatrices(client, requestId)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out := argOr(1, *matricesFlag)
+		if err := writeMatricesFile(out, requestMatrices{RequestId: requestId, Matrices: matrices}); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Wrote the matrices of request %v to %s", requestId, out)
+		return
+	}
+
+	if flag.Arg(0) == "scan" {
+		scanner, err := openLogScanner(context.Background(), client, common.HexToAddress(contractAddress), *deployBlockFlag, *scanFileFlag, defaultScanConfig())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := scanner.Scan(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		printRequests(scanner.Requests())
+		return
+	}
 
-	privateKey, err := getECDSAPrivateKey(os.Getenv("PRIVATE_KEY"))
+	archive, err := openBlobArchive(*archiveFlag)
 	if err != nil {
-		log.Fatalf("Error getting private key: %v", err)
+		log.Fatal(err)
 	}
 
-	nonce, chainID, tip, maxFeePerGas, err := prepareTransactionParams(client, privateKey)
+	if flag.Arg(0) == "fetch" {
+		source := &beaconBlobSource{client: client, beacon: beacon.NewClient(os.Getenv("BEACON_URL")), archive: archive}
+		if err := printFetchedResult(context.Background(), source, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "advise" {
+		txParams, err := prepareTransactionParams(client, blobFees)
+		if err != nil {
+			log.Fatal(err)
+		}
+		prices, err := livePostingPrices(txParams)
+		if err != nil {
+			log.Fatal(err)
+		}
+		requestId := big.NewInt(*requestIdFlag)
+		payload, err := serializeResult(resultMerkleRoot(), resultMatrix(), requestId)
+		if err != nil {
+			log.Fatal(err)
+		}
+		input := generateSubmitSolutionCalldata(resultMerkleRoot(), resultMatrix(), requestId)
+		costs, err := priceSubmission(prices, input, payload, *execGasFlag, encoder.Options{Codec: codec, Packing: packing})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := printPostingCosts(prices, costs); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	journal, err := openTxJournal(*journalFlag)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer journal.Close()
 
-	requestId, err := CheckLatestRequestId(client)
+	trackerCfg := defaultTrackerConfig()
+	trackerCfg.Confirmations = *confirmationsFlag
+
+	if flag.Arg(0) == "broadcast" {
+		signedTxs, requestId, err := broadcastTxFile(context.Background(), client, argOr(1, "signed-blob-txs."+*formatFlag), journal)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, signedTx := range signedTxs {
+			if err := archive.PutTransaction(signedTx, requestId, 0); err != nil {
+				log.Printf("Failed to archive blobs of %s: %v", signedTx.Hash().Hex(), err)
+			}
+		}
+		for _, signedTx := range signedTxs {
+			receipt, err := trackTransaction(context.Background(), client, signedTx, trackerCfg)
+			if err != nil {
+				if receipt != nil {
+					journal.Failed(signedTx, err)
+				}
+				log.Fatal(err)
+			}
+			if err := journal.Confirmed(signedTx, receipt.BlockNumber.Uint64()); err != nil {
+				log.Print(err)
+			}
+			if err := archive.PutTransaction(signedTx, requestId, receipt.BlockNumber.Uint64()); err != nil {
+				log.Printf("Failed to archive blobs of %s: %v", signedTx.Hash().Hex(), err)
+			}
+		}
+		client.logStats()
+		return
+	}
 
+	txSigner, err := openSigner(context.Background(), *signerFlag)
 	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeSigner(txSigner)
+
+	fromAddress := txSigner.Address()
+	nonces, err := openNonceManager(context.Background(), client, fromAddress, *nonceFileFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer nonces.Close()
+
+	replacementCfg := defaultReplacementConfig()
+	replacementCfg.StuckAfter = *stuckAfterFlag
+	if _, ok := replacementCfg.MaxSpend.SetString(*maxSpendFlag, 10); !ok {
+		log.Fatalf("Invalid maximum spend %q", *maxSpendFlag)
+	}
+	replacer := &replacementManager{client: client, signer: txSigner, cfg: replacementCfg, journal: journal}
+
+	if err := resumeJournal(context.Background(), client, journal, replacer, trackerCfg, archive, nonces); err != nil {
+		log.Fatal(err)
+	}
+	if err := nonces.ReleaseReserved(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	if flag.Arg(0) == "resume" {
 		return
 	}
-	requestId = big.NewInt(10)
-	// matrices, err := GetMatrices(client, requestId)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	// fmt.Print(requestId)
 
-	// matrixMul, singletonArray := MultiplyMatrices(matrices[0], matrices[1])
+	txParams, err := prepareTransactionParams(client, blobFees)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	requestId := big.NewInt(*requestIdFlag)
+	if requestId.Sign() <= 0 {
+		if requestId, err = latestRequestId(context.Background(), client, *scanFileFlag, *deployBlockFlag); err != nil {
+			log.Fatal(err)
+		}
+	} else if err := checkRequestReceived(context.Background(), client, *scanFileFlag, *deployBlockFlag, requestId); err != nil {
+		log.Fatal(err)
+	}
+	matrixMul, merkleRoot, err := solveRequest(client, requestId)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Submitting the result of request %v", requestId)
 
-	matrixMul := [3][3]*big.Int{
-		{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
-		{big.NewInt(4), big.NewInt(5), big.NewInt(6)},
-		{big.NewInt(7), big.NewInt(8), big.NewInt(9)},
+	blobTxs, err := buildResultTxs(txParams, func(count int) (uint64, error) {
+		return nonces.Reserve(context.Background(), count)
+	}, *layoutFlag, encoder.Options{Codec: codec, Packing: packing}, merkleRoot, matrixMul, requestId)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	singletonArray := [9]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4), big.NewInt(5), big.NewInt(6), big.NewInt(7), big.NewInt(8), big.NewInt(9)}
-	merkleRoot := MerkleTreeRoot(singletonArray[:])
+	for i, blobTx := range blobTxs {
+		if isBlobDataTx(blobTx) {
+			continue
+		}
+		gas, err := preflightTx(context.Background(), client, fromAddress, blobTx)
+		if err != nil {
+			releaseNonces(nonces, blobTxs)
+			log.Fatalf("Refusing to submit transaction %d of %d: %v", i+1, len(blobTxs), err)
+		}
+		if blobTxs[i], err = withGas(blobTx, gas); err != nil {
+			log.Fatal(err)
+		}
+	}
 
+	signedTxs := make([]*types.Transaction, 0, len(blobTxs))
+	for i, blobTx := range blobTxs {
+		signedTx, err := signTransaction(blobTx, txSigner)
+		if err != nil {
+			releaseNonces(nonces, blobTxs[i:])
+			log.Fatal(err)
+		}
+		if err := journal.Sent(signedTx, requestId); err != nil {
+			releaseNonces(nonces, blobTxs[i:])
+			log.Fatal(err)
+		}
+		if err := broadcastTransaction(context.Background(), client, signedTx); err != nil {
+			journal.Failed(signedTx, err)
+			releaseNonces(nonces, blobTxs[i:])
+			log.Fatal(err)
+		}
+		if err := archive.PutTransaction(signedTx, requestId, 0); err != nil {
+			log.Printf("Failed to archive blobs of %s: %v", signedTx.Hash().Hex(), err)
+		}
+		signedTxs = append(signedTxs, signedTx)
+	}
+
+	for _, signedTx := range signedTxs {
+		landedTx, err := replacer.waitForInclusion(context.Background(), signedTx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		receipt, err := trackTransaction(context.Background(), client, landedTx, trackerCfg)
+		if err != nil {
+			if receipt != nil {
+				journal.Failed(landedTx, err)
+			}
+			log.Fatal(err)
+		}
+		if err := journal.Confirmed(landedTx, receipt.BlockNumber.Uint64()); err != nil {
+			log.Print(err)
+		}
+		if err := nonces.Confirm(landedTx.Nonce()); err != nil {
+			log.Print(err)
+		}
+		if err := archive.PutTransaction(landedTx, requestId, receipt.BlockNumber.Uint64()); err != nil {
+			log.Printf("Failed to archive blobs of %s: %v", landedTx.Hash().Hex(), err)
+		}
+	}
+	client.logStats()
+}
+
+// buildResultTxs builds the unsigned blob transactions submitting matrixMul
+// for requestId. reserve is asked once for the first of count consecutive
+// nonces.
+func buildResultTxs(p *txParams, reserve func(count int) (uint64, error), layout string, opts encoder.Options, merkleRoot []byte, matrixMul [3][3]*big.Int, requestId *big.Int) ([]*types.Transaction, error) {
 	input := generateSubmitSolutionCalldata(merkleRoot, matrixMul, requestId)
 
-	blobTx, err := createBlobTx(chainID, nonce, tip, maxFeePerGas, merkleRoot, input)
+	if layout == "cells" {
+		sidecar, err := createCellSidecar(matrixMul)
+		if err != nil {
+			return nil, err
+		}
+		if p.Nonce, err = reserve(1); err != nil {
+			return nil, err
+		}
+		blobTx, err := createBlobTx(p, p.Nonce, sidecar, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create blob transaction: %v", err)
+		}
+		return []*types.Transaction{blobTx}, nil
+	}
+
+	payload, err := serializeResult(merkleRoot, matrixMul, requestId)
 	if err != nil {
-		log.Fatal("Failed to create blob transaction:", err)
+		return nil, err
+	}
+	plan, err := planBlobPayload(payload, defaultBlobPlanConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	if p.Nonce, err = reserve(len(plan)); err != nil {
+		return nil, err
+	}
+	blobTxs, err := createBlobTxs(p, plan, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob transactions: %v", err)
+	}
+	return blobTxs, nil
+}
+
+// releaseNonces hands the nonces of transactions that were never sent back to
+// the nonce manager.
+func releaseNonces(nonces *nonceManager, txs []*types.Transaction) {
+	for _, tx := range txs {
+		if err := nonces.Release(tx.Nonce()); err != nil {
+			log.Print(err)
+		}
 	}
+}
+
+// resultMerkleRoot is the Merkle root submitted with resultMatrix.
+func resultMerkleRoot() []byte {
+	singletonArray := [9]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4), big.NewInt(5), big.NewInt(6), big.NewInt(7), big.NewInt(8), big.NewInt(9)}
+	return MerkleTreeRoot(singletonArray[:])
+}
 
-	sendTransaction(client, blobTx, privateKey)
+// argOr returns positional argument i, or def if it was not given.
+func argOr(i int, def string) string {
+	if flag.NArg() > i {
+		return flag.Arg(i)
+	}
+	return def
 }
 
+// resultMatrix is the placeholder result advise prices and prove-cell opens,
+// as neither depends on the values of a real result.
+func resultMatrix() [3][3]*big.Int {
+	return [3][3]*big.Int{
+		{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+		{big.NewInt(4), big.NewInt(5), big.NewInt(6)},
+		{big.NewInt(7), big.NewInt(8), big.NewInt(9)},
+	}
+}
+
+// loadEnv reads .env if there is one. Without it, settings come from the
+// environment alone, as on an offline signing machine.
 func loadEnv() error {
-	return godotenv.Load()
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
 }
 
 func getECDSAPrivateKey(privateKeyHex string) (*ecdsa.PrivateKey, error) {
@@ -82,17 +504,89 @@ func getECDSAPrivateKey(privateKeyHex string) (*ecdsa.PrivateKey, error) {
 	return crypto.ToECDSA(privateKeyBytes)
 }
 
-func sendTransaction(client *ethclient.Client, tx *types.Transaction, privateKey *ecdsa.PrivateKey) {
-	signedTx, err := types.SignTx(tx, types.NewCancunSigner(tx.ChainId()), privateKey)
+// openSigner returns the signer backend named kind, configured from the
+// environment.
+func openSigner(ctx context.Context, kind string) (signer.Signer, error) {
+	switch kind {
+	case "key":
+		privateKey, err := getECDSAPrivateKey(os.Getenv("PRIVATE_KEY"))
+		if err != nil {
+			return nil, fmt.Errorf("error getting private key: %v", err)
+		}
+		return signer.NewKey(privateKey), nil
+	case "keystore":
+		passphrase, err := keystorePassphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		return signer.OpenKeystore(os.Getenv("KEYSTORE_FILE"), passphrase)
+	case "remote":
+		address := os.Getenv("SIGNER_ADDRESS")
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid SIGNER_ADDRESS %q", address)
+		}
+		return signer.DialRemote(ctx, os.Getenv("SIGNER_URL"), common.HexToAddress(address))
+	default:
+		return nil, fmt.Errorf("unknown signer %q", kind)
+	}
+}
 
+// closeSigner releases the connection of a remote signer.
+func closeSigner(s signer.Signer) {
+	if remote, ok := s.(*signer.Remote); ok {
+		remote.Close()
+	}
+}
+
+// keystorePassphrase returns KEYSTORE_PASSPHRASE if it is set, and otherwise
+// prompts for the passphrase on the terminal, twice if confirm is set, so it
+// does not have to be kept in the environment or in .env.
+func keystorePassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv("KEYSTORE_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to prompt for the keystore passphrase on, set KEYSTORE_PASSPHRASE")
+	}
+	passphrase, err := readPassphrase(fd, "Keystore passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := readPassphrase(fd, "Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+func readPassphrase(fd int, prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
 	if err != nil {
-		log.Fatalf("Error signing transaction: %v", err)
+		return "", fmt.Errorf("failed to read passphrase: %v", err)
 	}
+	return string(passphrase), nil
+}
+
+func signTransaction(tx *types.Transaction, txSigner signer.Signer) (*types.Transaction, error) {
+	if report := validateSidecar(tx, defaultTxpoolLimits()); !report.OK() {
+		return nil, report
+	}
+	return txSigner.SignTx(context.Background(), tx)
+}
 
-	err = client.SendTransaction(context.Background(), signedTx)
+func broadcastTransaction(ctx context.Context, client chainClient, signedTx *types.Transaction) error {
+	err := client.SendTransaction(ctx, signedTx)
 	if err != nil {
-		log.Fatalf("Failed to send transaction: %v", err)
-	} else {
-		log.Printf("Successfully sent transaction. txhash= %s", signedTx.Hash().Hex())
+		return fmt.Errorf("failed to send transaction: %v", err)
 	}
+	log.Printf("Successfully sent transaction. txhash= %s", signedTx.Hash().Hex())
+	return nil
 }
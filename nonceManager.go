@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// nonceState is what the nonce manager persists between runs.
+type nonceState struct {
+	Address  common.Address `json:"address"`
+	Next     uint64         `json:"next"`
+	Reserved []uint64       `json:"reserved"` // handed out, not yet mined or released
+	Released []uint64       `json:"released"` // handed out, then dropped; reused first
+}
+
+// nonceManager hands out nonces for one sender without asking the node for
+// every transaction, so concurrent submissions never share a nonce. It
+// resyncs with the node's pending nonce on every reservation and persists its
+// state so a restart picks up where the last run stopped. A lock file next to
+// the state keeps a second process from handing out the same nonces.
+type nonceManager struct {
+	mu     sync.Mutex
+	client chainClient
+	path   string
+	lock   io.Closer
+	state  nonceState
+}
+
+// openNonceManager locks and loads the state at path, if any, and reconciles
+// it with the node. Nonces a previous run reserved stay reserved until
+// ReleaseReserved is called.
+func openNonceManager(ctx context.Context, client chainClient, address common.Address, path string) (*nonceManager, error) {
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock nonce state: %v", err)
+	}
+	m, err := loadNonceManager(ctx, client, address, path)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	m.lock = lock
+	return m, nil
+}
+
+func loadNonceManager(ctx context.Context, client chainClient, address common.Address, path string) (*nonceManager, error) {
+	m := &nonceManager{client: client, path: path, state: nonceState{Address: address}}
+
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read nonce state: %v", err)
+	default:
+		var state nonceState
+		if err := json.Unmarshal(raw, &state); err != nil {
+			return nil, fmt.Errorf("failed to decode nonce state: %v", err)
+		}
+		if state.Address == address {
+			m.state = state
+		} else {
+			log.Printf("Ignoring nonce state of %s, now sending from %s", state.Address.Hex(), address.Hex())
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.resync(ctx); err != nil {
+		return nil, err
+	}
+	return m, m.save()
+}
+
+// Close releases the lock on the state file.
+func (m *nonceManager) Close() error {
+	return m.lock.Close()
+}
+
+// ReleaseReserved treats every nonce still reserved as dropped. It is called
+// once the journal has been reconciled: the nonces of transactions a
+// previous run sent are confirmed by then, so what is left was reserved for
+// transactions that were never sent.
+func (m *nonceManager) ReleaseReserved(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, n := range m.state.Reserved {
+		m.state.Released = insertNonce(m.state.Released, n)
+	}
+	m.state.Reserved = nil
+	if err := m.resync(ctx); err != nil {
+		return err
+	}
+	return m.save()
+}
+
+// Address is the sender whose nonces m hands out.
+func (m *nonceManager) Address() common.Address {
+	return m.state.Address
+}
+
+// Reserve hands out count consecutive nonces and returns the first. Dropped
+// nonces are reused first so they do not leave a gap that blocks everything
+// above them.
+func (m *nonceManager) Reserve(ctx context.Context, count int) (uint64, error) {
+	if count <= 0 {
+		return 0, fmt.Errorf("invalid nonce count %d", count)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.resync(ctx); err != nil {
+		return 0, err
+	}
+
+	first := m.state.Next
+	if len(m.state.Released) > 0 && m.rangeFree(m.state.Released[0], count) {
+		first = m.state.Released[0]
+	} else if len(m.state.Released) > 0 {
+		log.Printf("Nonce %d is free but %d consecutive nonces do not fit there", m.state.Released[0], count)
+	}
+
+	for n := first; n < first+uint64(count); n++ {
+		m.state.Released = removeNonce(m.state.Released, n)
+		m.state.Reserved = insertNonce(m.state.Reserved, n)
+	}
+	if end := first + uint64(count); end > m.state.Next {
+		m.state.Next = end
+	}
+	return first, m.save()
+}
+
+// Release returns a nonce whose transaction was never sent or was dropped.
+func (m *nonceManager) Release(nonce uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.Reserved = removeNonce(m.state.Reserved, nonce)
+	m.state.Released = insertNonce(m.state.Released, nonce)
+	return m.save()
+}
+
+// Confirm marks a nonce as used by a mined transaction.
+func (m *nonceManager) Confirm(nonce uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state.Reserved = removeNonce(m.state.Reserved, nonce)
+	return m.save()
+}
+
+// resync aligns the local state with the node's pending nonce. Everything
+// below it is used; a gap between it and the local counter that nothing
+// local accounts for is marked free so it gets filled.
+func (m *nonceManager) resync(ctx context.Context) error {
+	pending, err := m.client.PendingNonceAt(ctx, m.state.Address)
+	if err != nil {
+		return fmt.Errorf("error getting nonce: %v", err)
+	}
+
+	m.state.Reserved = dropBelow(m.state.Reserved, pending)
+	m.state.Released = dropBelow(m.state.Released, pending)
+	if pending >= m.state.Next {
+		m.state.Next = pending
+		return nil
+	}
+	for n := pending; n < m.state.Next; n++ {
+		if !containsNonce(m.state.Reserved, n) && !containsNonce(m.state.Released, n) {
+			log.Printf("Nonce %d was handed out but is unknown to the node, reusing it", n)
+			m.state.Released = insertNonce(m.state.Released, n)
+		}
+	}
+	// Free nonces at the top of the range are simply not handed out yet.
+	for len(m.state.Released) > 0 && m.state.Released[len(m.state.Released)-1] == m.state.Next-1 {
+		m.state.Released = m.state.Released[:len(m.state.Released)-1]
+		m.state.Next--
+	}
+	return nil
+}
+
+// rangeFree reports whether [first, first+count) only holds released or
+// never handed out nonces.
+func (m *nonceManager) rangeFree(first uint64, count int) bool {
+	for n := first; n < first+uint64(count); n++ {
+		if n < m.state.Next && !containsNonce(m.state.Released, n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *nonceManager) save() error {
+	raw, err := json.MarshalIndent(m.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(m.path, raw); err != nil {
+		return fmt.Errorf("failed to save nonce state: %v", err)
+	}
+	return nil
+}
+
+func containsNonce(nonces []uint64, n uint64) bool {
+	i := sort.Search(len(nonces), func(i int) bool { return nonces[i] >= n })
+	return i < len(nonces) && nonces[i] == n
+}
+
+func insertNonce(nonces []uint64, n uint64) []uint64 {
+	i := sort.Search(len(nonces), func(i int) bool { return nonces[i] >= n })
+	if i < len(nonces) && nonces[i] == n {
+		return nonces
+	}
+	nonces = append(nonces, 0)
+	copy(nonces[i+1:], nonces[i:])
+	nonces[i] = n
+	return nonces
+}
+
+func removeNonce(nonces []uint64, n uint64) []uint64 {
+	i := sort.Search(len(nonces), func(i int) bool { return nonces[i] >= n })
+	if i < len(nonces) && nonces[i] == n {
+		return append(nonces[:i], nonces[i+1:]...)
+	}
+	return nonces
+}
+
+func dropBelow(nonces []uint64, n uint64) []uint64 {
+	i := sort.Search(len(nonces), func(i int) bool { return nonces[i] >= n })
+	return append(nonces[:0], nonces[i:]...)
+}
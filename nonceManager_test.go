@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// pendingNonceClient answers PendingNonceAt with a fixed nonce.
+type pendingNonceClient struct {
+	chainClient
+	pending uint64
+}
+
+func (c *pendingNonceClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return c.pending, nil
+}
+
+func openTestNonceManager(t *testing.T, client chainClient, path string) *nonceManager {
+	t.Helper()
+	m, err := openNonceManager(context.Background(), client, common.HexToAddress("0x01"), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func reserveNonce(t *testing.T, m *nonceManager, count int) uint64 {
+	t.Helper()
+	n, err := m.Reserve(context.Background(), count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestNonceManagerReserveConfirmRelease(t *testing.T) {
+	client := &pendingNonceClient{pending: 3}
+	m := openTestNonceManager(t, client, filepath.Join(t.TempDir(), "nonces.json"))
+
+	if n := reserveNonce(t, m, 1); n != 3 {
+		t.Fatalf("first nonce %d, want the pending nonce 3", n)
+	}
+	if n := reserveNonce(t, m, 2); n != 4 {
+		t.Fatalf("batch starts at %d, want 4", n)
+	}
+	if n := reserveNonce(t, m, 1); n != 6 {
+		t.Fatalf("nonce %d, want 6", n)
+	}
+
+	// 3 is mined, 4 is dropped: 4 is handed out again before anything new.
+	if err := m.Confirm(3); err != nil {
+		t.Fatal(err)
+	}
+	client.pending = 4
+	if err := m.Release(4); err != nil {
+		t.Fatal(err)
+	}
+	if n := reserveNonce(t, m, 1); n != 4 {
+		t.Fatalf("nonce %d, want the released 4", n)
+	}
+
+	// A released nonce too few for a batch is skipped for it.
+	if err := m.Release(5); err != nil {
+		t.Fatal(err)
+	}
+	if n := reserveNonce(t, m, 2); n != 7 {
+		t.Fatalf("batch starts at %d, want 7", n)
+	}
+	if want := []uint64{4, 6, 7, 8}; !reflect.DeepEqual(m.state.Reserved, want) {
+		t.Fatalf("reserved %v, want %v", m.state.Reserved, want)
+	}
+	if want := []uint64{5}; !reflect.DeepEqual(m.state.Released, want) {
+		t.Fatalf("released %v, want %v", m.state.Released, want)
+	}
+
+	if _, err := m.Reserve(context.Background(), 0); err == nil {
+		t.Fatal("reserved zero nonces")
+	}
+}
+
+func TestNonceManagerPersists(t *testing.T) {
+	client := &pendingNonceClient{pending: 10}
+	path := filepath.Join(t.TempDir(), "nonces.json")
+
+	m := openTestNonceManager(t, client, path)
+	reserveNonce(t, m, 3)
+	if err := m.Release(11); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The node has seen 10 in the meantime. Nonce 12 stays reserved until the
+	// caller has reconciled its journal.
+	client.pending = 11
+	m = openTestNonceManager(t, client, path)
+	want := nonceState{Address: common.HexToAddress("0x01"), Next: 13, Reserved: []uint64{12}, Released: []uint64{11}}
+	if !reflect.DeepEqual(m.state, want) {
+		t.Fatalf("reopened with %+v, want %+v", m.state, want)
+	}
+	if n := reserveNonce(t, m, 1); n != 11 {
+		t.Fatalf("nonce %d, want the released 11", n)
+	}
+	if n := reserveNonce(t, m, 1); n != 13 {
+		t.Fatalf("nonce %d, want 13 while 12 is still reserved", n)
+	}
+}
+
+func TestNonceManagerIgnoresOtherSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nonces.json")
+	raw, _ := json.Marshal(nonceState{Address: common.HexToAddress("0x02"), Next: 9, Reserved: []uint64{8}})
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m := openTestNonceManager(t, &pendingNonceClient{pending: 2}, path)
+	if n := reserveNonce(t, m, 1); n != 2 {
+		t.Fatalf("nonce %d, want the node's 2", n)
+	}
+}
+
+func TestNonceManagerLocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nonces.json")
+	client := &pendingNonceClient{}
+
+	m, err := openNonceManager(context.Background(), client, common.HexToAddress("0x01"), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other, err := openNonceManager(context.Background(), client, common.HexToAddress("0x01"), path); err == nil {
+		other.Close()
+		t.Fatal("opened the nonce state twice")
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	openTestNonceManager(t, client, path)
+}
+
+func TestNonceManagerReleaseReserved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nonces.json")
+	raw, _ := json.Marshal(nonceState{Address: common.HexToAddress("0x01"), Next: 9, Reserved: []uint64{5, 6}, Released: []uint64{7}})
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := openTestNonceManager(t, &pendingNonceClient{pending: 5}, path)
+	if want := []uint64{5, 6}; !reflect.DeepEqual(m.state.Reserved, want) {
+		t.Fatalf("reserved after reopening %v, want %v", m.state.Reserved, want)
+	}
+	if err := m.ReleaseReserved(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Everything from the pending nonce up is free, so nothing is left to
+	// release and the counter falls back to the node's.
+	if m.state.Next != 5 || len(m.state.Released) != 0 || len(m.state.Reserved) != 0 {
+		t.Fatalf("state after releasing: %+v", m.state)
+	}
+
+	var got []uint64
+	for i := 0; i < 4; i++ {
+		got = append(got, reserveNonce(t, m, 1))
+	}
+	if want := []uint64{5, 6, 7, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("reserved nonces %v, want %v", got, want)
+	}
+}
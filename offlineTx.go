@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+
+	"blob/encoder"
+	"blob/rollup"
+	"blob/signer"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+)
+
+// offlineTx is the JSON form of a blob transaction moved between the build,
+// sign and broadcast steps. The transaction's own JSON encoding leaves out the
+// sidecar, so it is carried next to it.
+type offlineTx struct {
+	Transaction *types.Transaction `json:"transaction"`
+	Blobs       []hexutil.Bytes    `json:"blobs"`
+	Commitments []hexutil.Bytes    `json:"commitments"`
+	Proofs      []hexutil.Bytes    `json:"proofs"`
+}
+
+func newOfflineTx(tx *types.Transaction) offlineTx {
+	out := offlineTx{Transaction: tx.WithoutBlobTxSidecar()}
+	if sidecar := tx.BlobTxSidecar(); sidecar != nil {
+		for i := range sidecar.Blobs {
+			out.Blobs = append(out.Blobs, sidecar.Blobs[i][:])
+			out.Commitments = append(out.Commitments, sidecar.Commitments[i][:])
+			out.Proofs = append(out.Proofs, sidecar.Proofs[i][:])
+		}
+	}
+	return out
+}
+
+func (o offlineTx) tx() (*types.Transaction, error) {
+	if o.Transaction == nil {
+		return nil, errors.New("missing transaction")
+	}
+	if len(o.Blobs) == 0 {
+		return o.Transaction, nil
+	}
+	if len(o.Commitments) != len(o.Blobs) || len(o.Proofs) != len(o.Blobs) {
+		return nil, fmt.Errorf("sidecar has %d blobs, %d commitments and %d proofs", len(o.Blobs), len(o.Commitments), len(o.Proofs))
+	}
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       make([]kzg4844.Blob, len(o.Blobs)),
+		Commitments: make([]kzg4844.Commitment, len(o.Blobs)),
+		Proofs:      make([]kzg4844.Proof, len(o.Blobs)),
+	}
+	for i := range o.Blobs {
+		if len(o.Blobs[i]) != len(sidecar.Blobs[i]) || len(o.Commitments[i]) != len(sidecar.Commitments[i]) || len(o.Proofs[i]) != len(sidecar.Proofs[i]) {
+			return nil, fmt.Errorf("sidecar entry %d has the wrong size", i)
+		}
+		copy(sidecar.Blobs[i][:], o.Blobs[i])
+		copy(sidecar.Commitments[i][:], o.Commitments[i])
+		copy(sidecar.Proofs[i][:], o.Proofs[i])
+	}
+	return withSidecar(o.Transaction, sidecar)
+}
+
+// withSidecar attaches sidecar to a blob transaction, keeping its signature.
+func withSidecar(tx *types.Transaction, sidecar *types.BlobTxSidecar) (*types.Transaction, error) {
+	if tx.Type() != types.BlobTxType {
+		return nil, fmt.Errorf("transaction %s is not a blob transaction", tx.Hash().Hex())
+	}
+	v, r, s := tx.RawSignatureValues()
+	return types.NewTx(&types.BlobTx{
+		ChainID:    uint256.MustFromBig(tx.ChainId()),
+		Nonce:      tx.Nonce(),
+		GasTipCap:  uint256.MustFromBig(tx.GasTipCap()),
+		GasFeeCap:  uint256.MustFromBig(tx.GasFeeCap()),
+		Gas:        tx.Gas(),
+		To:         *tx.To(),
+		Value:      uint256.MustFromBig(tx.Value()),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+		BlobFeeCap: uint256.MustFromBig(tx.BlobGasFeeCap()),
+		BlobHashes: tx.BlobHashes(),
+		Sidecar:    sidecar,
+		V:          uint256.MustFromBig(v),
+		R:          uint256.MustFromBig(r),
+		S:          uint256.MustFromBig(s),
+	}), nil
+}
+
+// offlineTxParams builds transaction parameters from values given on the
+// command line instead of read from a node.
+func offlineTxParams(chainID uint64, tip, maxFee string, blobFeeCap uint64) (*txParams, error) {
+	if chainID == 0 {
+		return nil, errors.New("an offline build needs -chain-id")
+	}
+	tipWei, ok := new(big.Int).SetString(tip, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid priority fee %q", tip)
+	}
+	maxFeeWei, ok := new(big.Int).SetString(maxFee, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid fee cap %q", maxFee)
+	}
+	if maxFeeWei.Cmp(tipWei) < 0 {
+		return nil, fmt.Errorf("fee cap %v is below the priority fee %v", maxFeeWei, tipWei)
+	}
+	return &txParams{
+		ChainID:      new(big.Int).SetUint64(chainID),
+		Tip:          tipWei,
+		MaxFeePerGas: uint256.MustFromBig(maxFeeWei),
+		BlobFeeCap:   uint256.NewInt(blobFeeCap),
+	}, nil
+}
+
+// requestMatrices is a request's input as written by the matrices command,
+// so build can compute the result without a node.
+type requestMatrices struct {
+	RequestId *big.Int          `json:"requestId"`
+	Matrices  [2][3][3]*big.Int `json:"matrices"`
+}
+
+func writeMatricesFile(path string, m requestMatrices) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write matrices: %v", err)
+	}
+	return nil
+}
+
+func readMatricesFile(path string) (requestMatrices, error) {
+	var m requestMatrices
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return m, fmt.Errorf("failed to read matrices: %v", err)
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return m, fmt.Errorf("failed to decode matrices in %s: %v", path, err)
+	}
+	if m.RequestId == nil || m.RequestId.Sign() <= 0 {
+		return m, fmt.Errorf("%s names no request", path)
+	}
+	for _, matrix := range m.Matrices {
+		for _, row := range matrix {
+			for _, v := range row {
+				if v == nil {
+					return m, fmt.Errorf("%s is missing matrix entries", path)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// buildOfflineTxs builds the unsigned transactions submitting the result of
+// the request whose matrices are in the file at path. requestId, if positive,
+// must name the same request.
+func buildOfflineTxs(p *txParams, nonce uint64, layout string, opts encoder.Options, path string, requestId int64) ([]*types.Transaction, error) {
+	m, err := readMatricesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if requestId > 0 && m.RequestId.Cmp(big.NewInt(requestId)) != 0 {
+		return nil, fmt.Errorf("%s holds the matrices of request %v, not %d", path, m.RequestId, requestId)
+	}
+	matrixMul, merkleRoot := solveMatrices(m.Matrices)
+	return buildResultTxs(p, func(int) (uint64, error) {
+		return nonce, nil
+	}, layout, opts, merkleRoot, matrixMul, m.RequestId)
+}
+
+// writeTxFile stores txs at path, either as a JSON array of offlineTx or, for
+// rlp, as one hex encoded network transaction (with sidecar) per line.
+func writeTxFile(path, format string, txs []*types.Transaction) error {
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		out := make([]offlineTx, len(txs))
+		for i, tx := range txs {
+			out[i] = newOfflineTx(tx)
+		}
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	case "rlp":
+		for _, tx := range txs {
+			raw, err := tx.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			buf.WriteString(hexutil.Encode(raw))
+			buf.WriteByte('\n')
+		}
+	default:
+		return fmt.Errorf("unknown transaction format %q", format)
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// readTxFile reads a file written by writeTxFile, telling the format from its
+// first character. It returns the format so a signed file can be written back
+// the same way.
+func readTxFile(path string) ([]*types.Transaction, string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	raw = bytes.TrimSpace(raw)
+
+	if bytes.HasPrefix(raw, []byte("[")) {
+		var in []offlineTx
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return nil, "", fmt.Errorf("failed to decode %s: %v", path, err)
+		}
+		txs := make([]*types.Transaction, len(in))
+		for i := range in {
+			if txs[i], err = in[i].tx(); err != nil {
+				return nil, "", fmt.Errorf("transaction %d in %s: %v", i, path, err)
+			}
+		}
+		return txs, "json", nil
+	}
+
+	var txs []*types.Transaction
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		enc, err := hexutil.Decode(line)
+		if err != nil {
+			return nil, "", fmt.Errorf("transaction %d in %s: %v", len(txs), path, err)
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(enc); err != nil {
+			return nil, "", fmt.Errorf("transaction %d in %s: %v", len(txs), path, err)
+		}
+		txs = append(txs, tx)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	return txs, "rlp", nil
+}
+
+// isSigned reports whether tx carries a signature.
+func isSigned(tx *types.Transaction) bool {
+	v, r, s := tx.RawSignatureValues()
+	return v.Sign() != 0 || r.Sign() != 0 || s.Sign() != 0
+}
+
+// signTxFile signs every transaction in the file at in and writes the result
+// to out in the same format. It needs no node, so it can run on an
+// air-gapped machine.
+func signTxFile(in, out string, s signer.Signer) error {
+	txs, format, err := readTxFile(in)
+	if err != nil {
+		return err
+	}
+	signed := make([]*types.Transaction, len(txs))
+	for i, tx := range txs {
+		if isSigned(tx) {
+			return fmt.Errorf("transaction %d is already signed", i)
+		}
+		log.Printf("Signing transaction %d of %d. chain= %v nonce= %d to= %s gas= %d maxFee= %v tip= %v blobFeeCap= %v blobs= %d",
+			i+1, len(txs), tx.ChainId(), tx.Nonce(), tx.To().Hex(), tx.Gas(), tx.GasFeeCap(), tx.GasTipCap(), tx.BlobGasFeeCap(), len(tx.BlobHashes()))
+		if signed[i], err = signTransaction(tx, s); err != nil {
+			return err
+		}
+	}
+	return writeTxFile(out, format, signed)
+}
+
+// broadcastTxFile journals and sends the signed transactions in the file at
+// path in order and returns what was sent.
+func broadcastTxFile(ctx context.Context, client chainClient, path string, journal *txJournal) ([]*types.Transaction, *big.Int, error) {
+	txs, _, err := readTxFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	requestId, err := submittedRequestId(txs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, tx := range txs {
+		if !isSigned(tx) {
+			return nil, nil, fmt.Errorf("transaction %d is not signed", i)
+		}
+		if report := validateSidecar(tx, defaultTxpoolLimits()); !report.OK() {
+			return nil, nil, fmt.Errorf("transaction %d: %v", i, report)
+		}
+		sender, err := types.Sender(types.NewCancunSigner(tx.ChainId()), tx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("transaction %d has an invalid signature: %v", i, err)
+		}
+		log.Printf("Broadcasting transaction %d of %d from %s. nonce= %d", i+1, len(txs), sender.Hex(), tx.Nonce())
+	}
+	for _, tx := range txs {
+		if err := journal.Sent(tx, requestId); err != nil {
+			return nil, nil, err
+		}
+		if err := broadcastTransaction(ctx, client, tx); err != nil {
+			journal.Failed(tx, err)
+			return nil, nil, err
+		}
+	}
+	return txs, requestId, nil
+}
+
+// submittedRequestId returns the request whose result txs submit, read from
+// the submitResult call one of them makes.
+func submittedRequestId(txs []*types.Transaction) (*big.Int, error) {
+	var requestId *big.Int
+	for i, tx := range txs {
+		if isBlobDataTx(tx) {
+			continue
+		}
+		_, _, id, err := rollup.UnpackSubmitResult(tx.Data())
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		if requestId != nil && requestId.Cmp(id) != 0 {
+			return nil, fmt.Errorf("transactions submit results of requests %v and %v", requestId, id)
+		}
+		requestId = id
+	}
+	if requestId == nil {
+		return nil, fmt.Errorf("no transaction calls submitResult")
+	}
+	return requestId, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"blob/encoder"
+	"blob/rollup"
+)
+
+func TestOfflineSignWithoutEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	t.Setenv("PRIVATE_KEY", "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+	p, err := offlineTxParams(1, "1000000000", "30000000000", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs, err := buildResultTxs(p, func(int) (uint64, error) { return 3, nil }, "framed", encoder.Options{Codec: encoder.CodecNone, Packing: encoder.Packing31}, resultMerkleRoot(), resultMatrix(), big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, out := filepath.Join(dir, "txs.json"), filepath.Join(dir, "signed.json")
+	if err := writeTxFile(in, "json", txs); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loadEnv(); err != nil {
+		t.Fatalf("loading settings without a .env file: %v", err)
+	}
+	txSigner, err := openSigner(context.Background(), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signTxFile(in, out, txSigner); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, _, err := readTxFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestId, err := submittedRequestId(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requestId.Int64() != 5 {
+		t.Fatalf("decoded request %v, want 5", requestId)
+	}
+}
+
+func TestBuildOfflineTxsSolvesRequest(t *testing.T) {
+	var a, b [3][3]*big.Int
+	for i := range a {
+		for j := range a[i] {
+			a[i][j] = big.NewInt(int64(i + 2*j + 1))
+			b[i][j] = big.NewInt(int64(3*i - j))
+		}
+	}
+	want, _ := MultiplyMatrices(a, b)
+	path := filepath.Join(t.TempDir(), "matrices.json")
+	if err := writeMatricesFile(path, requestMatrices{RequestId: big.NewInt(7), Matrices: [2][3][3]*big.Int{a, b}}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := offlineTxParams(1, "1000000000", "30000000000", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, layout := range []string{"framed", "cells"} {
+		t.Run(layout, func(t *testing.T) {
+			txs, err := buildOfflineTxs(p, 3, layout, encoder.Options{Codec: encoder.CodecNone, Packing: encoder.Packing31}, path, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := txs[len(txs)-1]
+			_, results, requestId, err := rollup.UnpackSubmitResult(last.Data())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requestId.Int64() != 7 || !equalMatrices(results, want) {
+				t.Fatalf("calldata submits %v for request %v, want %v for 7", results, requestId, want)
+			}
+			if layout != "framed" {
+				return
+			}
+			payload, err := encoder.Decode(last.BlobTxSidecar().Blobs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, matrix, requestId, err := deserializeResult(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if requestId.Int64() != 7 || !equalMatrices(matrix, want) {
+				t.Fatalf("blob carries %v for request %v, want %v for 7", matrix, requestId, want)
+			}
+		})
+	}
+
+	if _, err := buildOfflineTxs(p, 3, "framed", encoder.Options{}, path, 8); err == nil {
+		t.Fatal("built request 7 when asked for request 8")
+	}
+}
+
+func equalMatrices(x, y [3][3]*big.Int) bool {
+	for i := range x {
+		for j := range x[i] {
+			if x[i][j].Cmp(y[i][j]) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"blob/encoder"
+
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// postingOption is a way of putting a result payload on chain.
+type postingOption string
+
+const (
+	postCalldata postingOption = "calldata" // payload appended to the submitResult calldata
+	postBlob     postingOption = "blob"     // payload in blobs, as submit does
+	postHybrid   postingOption = "hybrid"   // full blobs for the bulk, the remainder in calldata
+)
+
+// postingPrices are the prices a submission is expected to pay.
+type postingPrices struct {
+	BaseFee     *big.Int
+	Tip         *big.Int
+	BlobBaseFee *big.Int
+}
+
+// livePostingPrices derives the prices of the next block from the head p was
+// prepared against.
+func livePostingPrices(p *txParams) (postingPrices, error) {
+	head := p.Head
+	if head == nil || head.BaseFee == nil {
+		return postingPrices{}, errors.New("head block has no base fee")
+	}
+	if head.ExcessBlobGas == nil || head.BlobGasUsed == nil {
+		return postingPrices{}, fmt.Errorf("block %v has no blob gas fields, is Cancun active?", head.Number)
+	}
+	return postingPrices{
+		BaseFee:     head.BaseFee,
+		Tip:         p.Tip,
+		BlobBaseFee: eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(*head.ExcessBlobGas, *head.BlobGasUsed)),
+	}, nil
+}
+
+// postingCost is the price of one postingOption for a submission.
+type postingCost struct {
+	Option        postingOption
+	Txs           int
+	Gas           uint64 // intrinsic, calldata and execution gas of all transactions
+	CalldataBytes int
+	Blobs         int
+	GasFee        *big.Int
+	BlobFee       *big.Int
+	Total         *big.Int
+	Err           error // why the option cannot be used, if it cannot
+}
+
+// priceSubmission prices posting payload next to the submitResult call input
+// in each of the three ways. execGas is the gas the call itself uses beyond
+// the intrinsic and calldata gas.
+func priceSubmission(prices postingPrices, input, payload []byte, execGas uint64, opts encoder.Options) ([]postingCost, error) {
+	data, _, err := encoder.Compress(payload, opts.Codec, opts.Packing)
+	if err != nil {
+		return nil, err
+	}
+
+	blobCost := priceBlobPosting(prices, input, data, nil, execGas, opts.Packing)
+	blobCost.Option = postBlob
+
+	calldataCost := priceCalldataPosting(prices, append(append([]byte{}, input...), data...), execGas)
+
+	full := len(data) / opts.Packing.Capacity() * opts.Packing.Capacity()
+	var hybridCost postingCost
+	switch {
+	case full == 0:
+		hybridCost = calldataCost
+	case full == len(data):
+		hybridCost = blobCost
+	default:
+		hybridCost = priceBlobPosting(prices, input, data[:full], data[full:], execGas, opts.Packing)
+	}
+	hybridCost.Option = postHybrid
+
+	return []postingCost{calldataCost, blobCost, hybridCost}, nil
+}
+
+// priceBlobPosting prices posting data in blob transactions, split the way
+// planBlobPayload splits it, with tail appended to the calldata of the
+// transaction making the call. data is already compressed, so the blobs are
+// counted from its length rather than built.
+func priceBlobPosting(prices postingPrices, input, data, tail []byte, execGas uint64, packing encoder.Packing) postingCost {
+	partSize := defaultBlobPlanConfig(encoder.Options{Packing: packing}).MaxBlobsPerTx * packing.Capacity()
+	cost := postingCost{Txs: 1}
+	if len(data) > 0 {
+		cost.Txs = (len(data) + partSize - 1) / partSize
+	}
+	for i := 0; i < cost.Txs; i++ {
+		end := (i + 1) * partSize
+		if end > len(data) {
+			end = len(data)
+		}
+		cost.Blobs += encoder.BlobCount(end-i*partSize, packing)
+	}
+
+	// Only the last transaction carries calldata; the others post blobs alone.
+	call := append(append([]byte{}, input...), tail...)
+	cost.Gas = uint64(cost.Txs)*params.TxGas + calldataGas(call) + execGas
+	cost.CalldataBytes = len(call)
+	cost.price(prices)
+	return cost
+}
+
+// priceCalldataPosting prices a single transaction carrying everything in
+// calldata.
+func priceCalldataPosting(prices postingPrices, data []byte, execGas uint64) postingCost {
+	cost := postingCost{
+		Option:        postCalldata,
+		Txs:           1,
+		Gas:           params.TxGas + calldataGas(data) + execGas,
+		CalldataBytes: len(data),
+	}
+	if max := defaultTxpoolLimits().MaxTxSize; len(data) > max {
+		cost.Err = fmt.Errorf("%d bytes of calldata exceed the pool's %d byte transaction limit", len(data), max)
+	}
+	cost.price(prices)
+	return cost
+}
+
+func (c *postingCost) price(prices postingPrices) {
+	gasPrice := new(big.Int).Add(prices.BaseFee, prices.Tip)
+	c.GasFee = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(c.Gas))
+	c.BlobFee = new(big.Int).Mul(prices.BlobBaseFee, big.NewInt(int64(c.Blobs)*params.BlobTxBlobGasPerBlob))
+	c.Total = new(big.Int).Add(c.GasFee, c.BlobFee)
+}
+
+// calldataGas is the EIP-2028 intrinsic gas of data.
+func calldataGas(data []byte) uint64 {
+	var gas uint64
+	for _, b := range data {
+		if b == 0 {
+			gas += params.TxDataZeroGas
+		} else {
+			gas += params.TxDataNonZeroGasEIP2028
+		}
+	}
+	return gas
+}
+
+// cheapestPosting returns the usable option with the lowest total.
+func cheapestPosting(costs []postingCost) (postingCost, error) {
+	var best *postingCost
+	for i := range costs {
+		if costs[i].Err != nil {
+			continue
+		}
+		if best == nil || costs[i].Total.Cmp(best.Total) < 0 {
+			best = &costs[i]
+		}
+	}
+	if best == nil {
+		return postingCost{}, errors.New("no posting option is usable")
+	}
+	return *best, nil
+}
+
+func printPostingCosts(prices postingPrices, costs []postingCost) error {
+	fmt.Printf("base fee %v wei, tip %v wei, blob base fee %v wei\n", prices.BaseFee, prices.Tip, prices.BlobBaseFee)
+	fmt.Printf("%-9s %4s %9s %9s %6s %22s %22s %22s\n", "option", "txs", "gas", "calldata", "blobs", "gas fee (wei)", "blob fee (wei)", "total (wei)")
+	for _, c := range costs {
+		fmt.Printf("%-9s %4d %9d %9d %6d %22v %22v %22v", c.Option, c.Txs, c.Gas, c.CalldataBytes, c.Blobs, c.GasFee, c.BlobFee, c.Total)
+		if c.Err != nil {
+			fmt.Printf("  unusable: %v", c.Err)
+		}
+		fmt.Println()
+	}
+	best, err := cheapestPosting(costs)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("cheapest: %s\n", best.Option)
+	return nil
+}
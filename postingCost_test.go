@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"blob/encoder"
+)
+
+func TestPriceBlobPostingCountsPlannedBlobs(t *testing.T) {
+	prices := postingPrices{BaseFee: big.NewInt(10), Tip: big.NewInt(1), BlobBaseFee: big.NewInt(1)}
+	input := []byte{0xde, 0xad, 0, 0}
+	for _, packing := range []encoder.Packing{encoder.Packing31, encoder.Packing254} {
+		capacity := packing.Capacity()
+		for _, length := range []int{0, 1, capacity, capacity + 1} {
+			data := make([]byte, length)
+			plan, err := planBlobPayload(data, defaultBlobPlanConfig(encoder.Options{Packing: packing}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			blobs := 0
+			for _, part := range plan {
+				blobs += len(part.Sidecar.Blobs)
+			}
+
+			cost := priceBlobPosting(prices, input, data, []byte{1}, 0, packing)
+			if cost.Txs != len(plan) || cost.Blobs != blobs {
+				t.Errorf("%v packing, %d bytes: priced %d txs and %d blobs, planned %d and %d", packing, length, cost.Txs, cost.Blobs, len(plan), blobs)
+			}
+			if want := uint64(len(plan))*21000 + calldataGas(append(input, 1)); cost.Gas != want {
+				t.Errorf("%v packing, %d bytes: gas %d, want %d", packing, length, cost.Gas, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"blob/rollup"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// gasLimitMargin is the percentage added on top of the node's estimate, so
+// state changes between estimation and inclusion do not run the call out of
+// gas.
+const gasLimitMargin = 20
+
+// revertError is a call the node reports would revert.
+type revertError struct {
+	Reason string // decoded reason, or a description of undecodable data
+	Data   []byte // raw revert data
+}
+
+func (e *revertError) Error() string {
+	return "execution would revert: " + e.Reason
+}
+
+// preflightTx simulates tx from the given sender at the latest block and
+// returns a gas limit for it. A call that would revert is reported as a
+// *revertError carrying the decoded reason.
+//
+// Calls are simulated without the blob fields, which this geth client cannot
+// pass to eth_call; the contract does not read them. They also leave out the
+// fee caps: with no gas limit set the node would charge them for its whole
+// call gas cap and could refuse the call for lack of funds.
+func preflightTx(ctx context.Context, client chainClient, from common.Address, tx *types.Transaction) (uint64, error) {
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+
+	if _, err := client.CallContract(ctx, msg, nil); err != nil {
+		return 0, decodeCallError(err)
+	}
+	gas, err := client.EstimateGas(ctx, msg)
+	if err != nil {
+		return 0, decodeCallError(err)
+	}
+	return gas + gas*gasLimitMargin/100, nil
+}
+
+// decodeCallError turns the revert data attached to an RPC error into a
+// readable reason. Errors without revert data are returned unchanged.
+func decodeCallError(err error) error {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		if strings.Contains(err.Error(), "execution reverted") {
+			return &revertError{Reason: "no reason given"}
+		}
+		return err
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return err
+	}
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil {
+		return err
+	}
+	return &revertError{Reason: decodeRevertReason(data), Data: data}
+}
+
+// decodeRevertReason decodes Error(string), Panic(uint256) and any custom
+// error declared in the contract ABI.
+func decodeRevertReason(data []byte) string {
+	if len(data) == 0 {
+		return "no reason given"
+	}
+	if reason, err := rollup.RevertReason(data); err == nil {
+		return reason
+	}
+	return fmt.Sprintf("unknown revert data %s", hexutil.Encode(data))
+}
+
+// withGas returns an unsigned copy of a blob transaction with a new gas limit.
+func withGas(tx *types.Transaction, gas uint64) (*types.Transaction, error) {
+	return copyBlobTx(tx, func(inner *types.BlobTx) {
+		inner.Gas = gas
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"blob/rollup"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// callError is an RPC error carrying revert data, like the ones eth_call and
+// eth_estimateGas return.
+type callError struct {
+	msg  string
+	data interface{}
+}
+
+func (e *callError) Error() string          { return e.msg }
+func (e *callError) ErrorCode() int         { return 3 }
+func (e *callError) ErrorData() interface{} { return e.data }
+
+// revertData packs args as the arguments of the error with the given
+// signature, e.g. "Error(string)".
+func revertData(t *testing.T, signature string, types []string, args ...interface{}) string {
+	t.Helper()
+	var arguments abi.Arguments
+	for _, name := range types {
+		typ, err := abi.NewType(name, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		arguments = append(arguments, abi.Argument{Type: typ})
+	}
+	packed, err := arguments.Pack(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hexutil.Encode(append(crypto.Keccak256([]byte(signature))[:4], packed...))
+}
+
+func TestDecodeCallError(t *testing.T) {
+	// The contract declares no custom errors yet; add one for the test.
+	uint256Type, _ := abi.NewType("uint256", "", nil)
+	custom := abi.NewError("RequestNotFound", abi.Arguments{{Name: "requestId", Type: uint256Type}})
+	rollup.ABI.Errors[custom.Name] = custom
+	t.Cleanup(func() { delete(rollup.ABI.Errors, custom.Name) })
+
+	tests := []struct {
+		name   string
+		err    error
+		reason string // empty if the error must come back unchanged
+	}{
+		{
+			name:   "reason string",
+			err:    &callError{"execution reverted: no such request", revertData(t, "Error(string)", []string{"string"}, "no such request")},
+			reason: "no such request",
+		},
+		{
+			name:   "panic",
+			err:    &callError{"execution reverted", revertData(t, "Panic(uint256)", []string{"uint256"}, big.NewInt(0x12))},
+			reason: "division or modulo by zero",
+		},
+		{
+			name:   "declared custom error",
+			err:    &callError{"execution reverted", revertData(t, "RequestNotFound(uint256)", []string{"uint256"}, big.NewInt(7))},
+			reason: "RequestNotFound[7]",
+		},
+		{
+			name:   "undeclared custom error",
+			err:    &callError{"execution reverted", "0xdeadbeef"},
+			reason: "unknown revert data 0xdeadbeef",
+		},
+		{
+			name:   "empty revert data",
+			err:    &callError{"execution reverted", "0x"},
+			reason: "no reason given",
+		},
+		{
+			name:   "revert without data",
+			err:    errors.New("execution reverted"),
+			reason: "no reason given",
+		},
+		{
+			name: "other error",
+			err:  errors.New("insufficient funds for gas * price + value"),
+		},
+		{
+			name: "undecodable data",
+			err:  &callError{"execution reverted", "not hex"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decodeCallError(tt.err)
+			var revert *revertError
+			if !errors.As(err, &revert) {
+				if tt.reason != "" {
+					t.Fatalf("got %v, want a revert with reason %q", err, tt.reason)
+				}
+				if err != tt.err {
+					t.Fatalf("got %v, want the error unchanged", err)
+				}
+				return
+			}
+			if revert.Reason != tt.reason {
+				t.Fatalf("reason %q, want %q", revert.Reason, tt.reason)
+			}
+			if !strings.HasSuffix(err.Error(), tt.reason) {
+				t.Fatalf("error %q does not end with the reason", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"time"
+
+	"blob/rollup"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type watcherConfig struct {
+	PollInterval  time.Duration // between polls, and between checks for newly confirmed blocks when subscribed
+	MaxRange      uint64        // blocks per FilterLogs request
+	Confirmations uint64        // blocks behind the head events are delivered at, so reorgs cannot undo them
+	MinBackoff    time.Duration // first reconnect delay, doubled up to MaxBackoff
+	MaxBackoff    time.Duration
+}
+
+func defaultWatcherConfig() watcherConfig {
+	return watcherConfig{
+		PollInterval:  12 * time.Second,
+		MaxRange:      2000,
+		Confirmations: defaultScanConfig().Confirmations,
+		MinBackoff:    time.Second,
+		MaxBackoff:    time.Minute,
+	}
+}
+
+// watcherCheckpoint is what the watcher persists: the last block every log of
+// which has been handled, and the position of the last delivered log.
+type watcherCheckpoint struct {
+	Scanned  uint64 `json:"scanned"`
+	Block    uint64 `json:"block"`
+	LogIndex uint   `json:"logIndex"`
+}
+
+// after reports whether l comes after the last delivered log.
+func (c *watcherCheckpoint) after(l types.Log) bool {
+	if l.BlockNumber != c.Block {
+		return l.BlockNumber > c.Block
+	}
+	return l.Index > c.LogIndex
+}
+
+// receiptWatcher follows NewReceipt events of the Rollup contract. Events are
+// delivered once the configured number of blocks is built on top of them,
+// read with FilterLogs from the checkpoint up to that depth, so a reorg
+// shallower than that can neither hide nor retract one. Over websocket, when
+// wsURL is set, a log subscription wakes the watcher as soon as an event is
+// emitted; otherwise client is polled. Each event is delivered once, in chain
+// order; the checkpoint is written as soon as the receiver has taken it.
+type receiptWatcher struct {
+	client     chainClient
+	wsURL      string
+	address    common.Address
+	checkpoint string
+	cfg        watcherConfig
+
+	state watcherCheckpoint
+	out   chan *rollup.NewReceipt
+}
+
+// newReceiptWatcher resumes from the checkpoint file, or starts at fromBlock
+// when there is none.
+func newReceiptWatcher(client chainClient, wsURL string, address common.Address, checkpoint string, fromBlock uint64, cfg watcherConfig) (*receiptWatcher, error) {
+	w := &receiptWatcher{
+		client:     client,
+		wsURL:      wsURL,
+		address:    address,
+		checkpoint: checkpoint,
+		cfg:        cfg,
+		out:        make(chan *rollup.NewReceipt),
+	}
+	raw, err := os.ReadFile(checkpoint)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if fromBlock > 0 {
+			w.state.Scanned = fromBlock - 1
+			w.state.Block = fromBlock - 1
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to read watcher checkpoint: %v", err)
+	default:
+		if err := json.Unmarshal(raw, &w.state); err != nil {
+			return nil, fmt.Errorf("failed to decode watcher checkpoint: %v", err)
+		}
+	}
+	return w, nil
+}
+
+// Receipts is the channel events are delivered on. It is closed when Run
+// returns.
+func (w *receiptWatcher) Receipts() <-chan *rollup.NewReceipt {
+	return w.out
+}
+
+// Run watches until ctx is cancelled, reconnecting with exponential backoff.
+func (w *receiptWatcher) Run(ctx context.Context) error {
+	defer close(w.out)
+
+	backoff := w.cfg.MinBackoff
+	for {
+		connected := false
+		var err error
+		if w.wsURL != "" {
+			err = w.subscribe(ctx, &connected)
+			if errors.Is(err, errSubscriptionsUnsupported) {
+				log.Printf("Receipt watcher falling back to polling: %v", err)
+				w.wsURL = ""
+				continue
+			}
+		} else {
+			err = w.poll(ctx, &connected)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			backoff = w.cfg.MinBackoff
+		}
+		log.Printf("Receipt watcher disconnected: %v, retrying in %v", err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+	}
+}
+
+var errSubscriptionsUnsupported = errors.New("endpoint does not support log subscriptions")
+
+// subscribe opens the subscription before catching up, so no wake-up is lost
+// in between, and catches up again whenever it fires or PollInterval passes.
+func (w *receiptWatcher) subscribe(ctx context.Context, connected *bool) error {
+	ws, err := ethclient.DialContext(ctx, w.wsURL)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	logs := make(chan types.Log, 128)
+	sub, err := ws.SubscribeFilterLogs(ctx, w.query(nil, nil), logs)
+	if err != nil {
+		if isNotSupported(err) {
+			return fmt.Errorf("%w: %v", errSubscriptionsUnsupported, err)
+		}
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	if err := w.catchUp(ctx); err != nil {
+		return err
+	}
+	*connected = true
+	log.Printf("Receipt watcher subscribed, caught up to block %d", w.state.Scanned)
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return err
+		case <-logs:
+			drainLogs(logs)
+		case <-ticker.C:
+		}
+		if err := w.catchUp(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// drainLogs empties logs; the subscription only signals that there is
+// something to catch up on.
+func drainLogs(logs <-chan types.Log) {
+	for {
+		select {
+		case <-logs:
+		default:
+			return
+		}
+	}
+}
+
+// poll catches up every PollInterval.
+func (w *receiptWatcher) poll(ctx context.Context, connected *bool) error {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.catchUp(ctx); err != nil {
+			return err
+		}
+		*connected = true
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// catchUp handles every event after the checkpoint up to the confirmed head,
+// MaxRange blocks at a time, checkpointing after each range.
+func (w *receiptWatcher) catchUp(ctx context.Context) error {
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if head < w.cfg.Confirmations {
+		return nil
+	}
+	target := head - w.cfg.Confirmations
+
+	for w.state.Scanned < target {
+		from := w.state.Scanned + 1
+		to := from + w.cfg.MaxRange - 1
+		if to > target {
+			to = target
+		}
+		logs, err := w.client.FilterLogs(ctx, w.query(new(big.Int).SetUint64(from), new(big.Int).SetUint64(to)))
+		if err != nil {
+			return fmt.Errorf("failed to fetch receipts in blocks %d-%d: %v", from, to, err)
+		}
+		for _, l := range logs {
+			if err := w.handle(ctx, l); err != nil {
+				return err
+			}
+		}
+		w.state.Scanned = to
+		if err := w.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle delivers l unless it was delivered before.
+func (w *receiptWatcher) handle(ctx context.Context, l types.Log) error {
+	if !w.state.after(l) {
+		return nil
+	}
+	receipt, err := rollup.UnpackNewReceipt(l)
+	if err != nil {
+		log.Printf("Skipping log %d of block %d: %v", l.Index, l.BlockNumber, err)
+		return nil
+	}
+	select {
+	case w.out <- receipt:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	w.state.Block, w.state.LogIndex = l.BlockNumber, l.Index
+	return w.save()
+}
+
+func (w *receiptWatcher) query(from, to *big.Int) ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		FromBlock: from,
+		ToBlock:   to,
+		Addresses: []common.Address{w.address},
+		Topics:    [][]common.Hash{{rollup.NewReceiptID}},
+	}
+}
+
+func (w *receiptWatcher) save() error {
+	raw, err := json.Marshal(w.state)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(w.checkpoint, raw); err != nil {
+		return fmt.Errorf("failed to save watcher checkpoint: %v", err)
+	}
+	return nil
+}
+
+// isNotSupported reports whether the endpoint cannot serve subscriptions at
+// all, as opposed to failing to open this one.
+func isNotSupported(err error) bool {
+	var rpcErr rpc.Error
+	return errors.Is(err, rpc.ErrNotificationsUnsupported) || (errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601)
+}
+
+// watchReceipts prints every new request until interrupted.
+func watchReceipts(ctx context.Context, client chainClient, checkpoint string, fromBlock uint64) error {
+	if fromBlock == 0 {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fetch head block number: %v", err)
+		}
+		fromBlock = head + 1
+	}
+	watcher, err := newReceiptWatcher(client, os.Getenv("NODE_WS_URL"), common.HexToAddress(contractAddress), checkpoint, fromBlock, defaultWatcherConfig())
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx) }()
+
+	for receipt := range watcher.Receipts() {
+		fmt.Printf("request %v from %s (block %d, tx %s)\n", receipt.RequestId, receipt.Sender.Hex(), receipt.Block, receipt.TxHash.Hex())
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blob/rollup"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// logChain serves NewReceipt logs and a head that tests move around.
+type logChain struct {
+	chainClient
+	mu   sync.Mutex
+	head uint64
+	logs []types.Log
+}
+
+func newReceiptLog(block uint64, index uint, requestId int64) types.Log {
+	data := make([]byte, 32)
+	data[31] = 0xaa
+	return types.Log{
+		Address:     common.HexToAddress(contractAddress),
+		Topics:      []common.Hash{rollup.NewReceiptID, common.BigToHash(big.NewInt(requestId))},
+		Data:        data,
+		BlockNumber: block,
+		Index:       index,
+	}
+}
+
+func (c *logChain) set(head uint64, logs ...types.Log) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head, c.logs = head, logs
+}
+
+func (c *logChain) BlockNumber(ctx context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.head, nil
+}
+
+func (c *logChain) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var logs []types.Log
+	for _, l := range c.logs {
+		if l.BlockNumber >= q.FromBlock.Uint64() && l.BlockNumber <= q.ToBlock.Uint64() {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func expectReceipts(t *testing.T, w *receiptWatcher, ids ...int64) {
+	t.Helper()
+	for _, id := range ids {
+		select {
+		case r := <-w.Receipts():
+			if r.RequestId.Int64() != id {
+				t.Fatalf("got request %v, want %d", r.RequestId, id)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("timed out waiting for request %d", id)
+		}
+	}
+	select {
+	case r := <-w.Receipts():
+		t.Fatalf("unexpected request %v", r.RequestId)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func testWatcherConfig(confirmations uint64) watcherConfig {
+	return watcherConfig{PollInterval: 10 * time.Millisecond, MaxRange: 4, Confirmations: confirmations, MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+}
+
+func TestReceiptWatcherWaitsOutReorgs(t *testing.T) {
+	chain := &logChain{}
+	chain.set(20, newReceiptLog(10, 0, 1), newReceiptLog(18, 0, 5))
+
+	w, err := newReceiptWatcher(chain, "", common.HexToAddress(contractAddress), filepath.Join(t.TempDir(), "cp.json"), 1, testWatcherConfig(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+	expectReceipts(t, w, 1)
+
+	// Block 18 is replaced before it is confirmed, by one logging another
+	// request at the same position.
+	chain.set(22, newReceiptLog(10, 0, 1), newReceiptLog(18, 0, 6), newReceiptLog(19, 0, 7))
+	expectReceipts(t, w, 6, 7)
+}
+
+// logStream is an eth namespace serving log subscriptions from a channel.
+type logStream struct {
+	logs chan types.Log
+}
+
+func (s *logStream) Logs(ctx context.Context, crit map[string]interface{}) (*rpc.Subscription, error) {
+	notifier, _ := rpc.NotifierFromContext(ctx)
+	sub := notifier.CreateSubscription()
+	go func() {
+		for {
+			select {
+			case l := <-s.logs:
+				notifier.Notify(sub.ID, l)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+func TestReceiptWatcherSubscription(t *testing.T) {
+	stream := &logStream{logs: make(chan types.Log, 1)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", stream); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer httpServer.Close()
+
+	chain := &logChain{}
+	chain.set(5, newReceiptLog(5, 0, 1))
+	cfg := testWatcherConfig(0)
+	cfg.PollInterval = time.Hour // only the subscription can wake the watcher
+
+	w, err := newReceiptWatcher(chain, "ws"+strings.TrimPrefix(httpServer.URL, "http"), common.HexToAddress(contractAddress), filepath.Join(t.TempDir(), "cp.json"), 1, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+	expectReceipts(t, w, 1)
+
+	l := newReceiptLog(6, 2, 2)
+	chain.set(6, newReceiptLog(5, 0, 1), l)
+	stream.logs <- l
+	expectReceipts(t, w, 2)
+}
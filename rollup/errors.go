@@ -0,0 +1,34 @@
+package rollup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// ErrUnknownRevert is returned by RevertReason for revert data that is not a
+// reason string, a panic or an error declared in the ABI.
+var ErrUnknownRevert = errors.New("unknown revert data")
+
+// RevertReason decodes the data of a reverted call: Error(string),
+// Panic(uint256) or any custom error the contract declares.
+func RevertReason(data []byte) (string, error) {
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason, nil
+	}
+	if len(data) >= 4 {
+		for name, abiErr := range ABI.Errors {
+			if !bytes.Equal(abiErr.ID[:4], data[:4]) {
+				continue
+			}
+			args, err := abiErr.Inputs.Unpack(data[4:])
+			if err != nil {
+				return name, nil
+			}
+			return fmt.Sprintf("%s%v", name, args), nil
+		}
+	}
+	return "", ErrUnknownRevert
+}
@@ -0,0 +1,189 @@
+package rollup
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Event signatures, the first topic of every log the contract emits.
+var (
+	DisputeRaisedID      = ABI.Events["DisputeRaised"].ID
+	NewReceiptID         = ABI.Events["NewReceipt"].ID
+	OperatorRegisteredID = ABI.Events["OperatorRegistered"].ID
+	PenaltyAppliedID     = ABI.Events["PenaltyApplied"].ID
+	ResultSubmittedID    = ABI.Events["ResultSubmitted"].ID
+	SuccessfulDisputeID  = ABI.Events["SuccessfulDispute"].ID
+)
+
+// Meta locates the log an event was decoded from. Events embed it last,
+// because the abi package unpacks a lone data argument into the first field.
+type Meta struct {
+	Address   common.Address
+	Block     uint64
+	BlockHash common.Hash
+	TxHash    common.Hash
+	TxIndex   uint
+	LogIndex  uint
+	Removed   bool // the log was reverted by a reorg
+}
+
+func metaOf(log types.Log) Meta {
+	return Meta{
+		Address:   log.Address,
+		Block:     log.BlockNumber,
+		BlockHash: log.BlockHash,
+		TxHash:    log.TxHash,
+		TxIndex:   log.TxIndex,
+		LogIndex:  log.Index,
+		Removed:   log.Removed,
+	}
+}
+
+// Metadata returns where the event was logged.
+func (m Meta) Metadata() Meta { return m }
+
+func (m *Meta) setMeta(meta Meta) { *m = meta }
+
+// Event is any decoded Logger event.
+type Event interface {
+	EventName() string
+	Metadata() Meta
+}
+
+// DisputeRaised is emitted when disputer challenges the result of RequestId.
+type DisputeRaised struct {
+	Disputer  common.Address
+	RequestId *big.Int
+	Meta
+}
+
+// NewReceipt is emitted when sender posts a new pair of matrices.
+type NewReceipt struct {
+	Sender    common.Address
+	RequestId *big.Int
+	Meta
+}
+
+// OperatorRegistered is emitted when an operator stakes StakeAmount.
+type OperatorRegistered struct {
+	Operator    common.Address
+	StakeAmount *big.Int
+	Meta
+}
+
+// PenaltyApplied is emitted when solver is penalised.
+type PenaltyApplied struct {
+	Solver    common.Address
+	Penalties *big.Int
+	Meta
+}
+
+// ResultSubmitted is emitted when solver submits the result of RequestId.
+type ResultSubmitted struct {
+	Solver     common.Address
+	RequestId  *big.Int
+	ResultRoot [32]byte
+	Meta
+}
+
+// SuccessfulDispute is emitted when a dispute by disputer succeeds.
+type SuccessfulDispute struct {
+	Disputer common.Address
+	Meta
+}
+
+func (*DisputeRaised) EventName() string      { return "DisputeRaised" }
+func (*NewReceipt) EventName() string         { return "NewReceipt" }
+func (*OperatorRegistered) EventName() string { return "OperatorRegistered" }
+func (*PenaltyApplied) EventName() string     { return "PenaltyApplied" }
+func (*ResultSubmitted) EventName() string    { return "ResultSubmitted" }
+func (*SuccessfulDispute) EventName() string  { return "SuccessfulDispute" }
+
+// DecodeEvent decodes any event of the contract into its typed struct. It
+// fails on logs of other events.
+func DecodeEvent(log types.Log) (Event, error) {
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("anonymous log %d of block %d", log.Index, log.BlockNumber)
+	}
+	var ev Event
+	switch log.Topics[0] {
+	case DisputeRaisedID:
+		ev = &DisputeRaised{}
+	case NewReceiptID:
+		ev = &NewReceipt{}
+	case OperatorRegisteredID:
+		ev = &OperatorRegistered{}
+	case PenaltyAppliedID:
+		ev = &PenaltyApplied{}
+	case ResultSubmittedID:
+		ev = &ResultSubmitted{}
+	case SuccessfulDisputeID:
+		ev = &SuccessfulDispute{}
+	default:
+		return nil, fmt.Errorf("unknown event %s in log %d of block %d", log.Topics[0].Hex(), log.Index, log.BlockNumber)
+	}
+	if err := unpackEvent(ev, log); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+func UnpackDisputeRaised(log types.Log) (*DisputeRaised, error) {
+	ev := &DisputeRaised{}
+	return ev, unpackEvent(ev, log)
+}
+
+func UnpackNewReceipt(log types.Log) (*NewReceipt, error) {
+	ev := &NewReceipt{}
+	return ev, unpackEvent(ev, log)
+}
+
+func UnpackOperatorRegistered(log types.Log) (*OperatorRegistered, error) {
+	ev := &OperatorRegistered{}
+	return ev, unpackEvent(ev, log)
+}
+
+func UnpackPenaltyApplied(log types.Log) (*PenaltyApplied, error) {
+	ev := &PenaltyApplied{}
+	return ev, unpackEvent(ev, log)
+}
+
+func UnpackResultSubmitted(log types.Log) (*ResultSubmitted, error) {
+	ev := &ResultSubmitted{}
+	return ev, unpackEvent(ev, log)
+}
+
+func UnpackSuccessfulDispute(log types.Log) (*SuccessfulDispute, error) {
+	ev := &SuccessfulDispute{}
+	return ev, unpackEvent(ev, log)
+}
+
+// unpackEvent fills ev from the data, indexed topics and position of log
+// after checking that log is the event ev stands for.
+func unpackEvent(ev Event, log types.Log) error {
+	name := ev.EventName()
+	event := ABI.Events[name]
+	if len(log.Topics) == 0 || log.Topics[0] != event.ID {
+		return fmt.Errorf("log is not a %s event", name)
+	}
+	if len(log.Data) > 0 {
+		if err := ABI.UnpackIntoInterface(ev, name, log.Data); err != nil {
+			return fmt.Errorf("failed to unpack %s: %v", name, err)
+		}
+	}
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(ev, indexed, log.Topics[1:]); err != nil {
+		return fmt.Errorf("failed to unpack %s topics: %v", name, err)
+	}
+	ev.(interface{ setMeta(Meta) }).setMeta(metaOf(log))
+	return nil
+}
@@ -0,0 +1,179 @@
+// Package rollup is a typed binding for the Rollup contract. The ABI is
+// embedded at build time, so calls are packed and unpacked without reading
+// anything from disk.
+//
+// The bindings are written by hand, not generated: calls are packed by method
+// name, so the compiler does not check them against the ABI. rollup_test.go
+// does, at test time, by comparing the signature and ID of every method and
+// event the bindings use with the embedded ABI.
+package rollup
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//go:embed abi.json
+var abiJSON string
+
+// ABI is the parsed Rollup ABI.
+var ABI = mustParseABI()
+
+func mustParseABI() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(fmt.Sprintf("rollup: invalid embedded ABI: %v", err))
+	}
+	return parsed
+}
+
+// CallOpts tunes a read-only call. A nil *CallOpts calls against the latest
+// block with a background context.
+type CallOpts struct {
+	Context     context.Context
+	BlockNumber *big.Int // nil for latest
+	From        common.Address
+}
+
+// Rollup reads from a deployed Rollup contract.
+type Rollup struct {
+	Address common.Address
+	caller  ethereum.ContractCaller
+}
+
+func New(address common.Address, caller ethereum.ContractCaller) *Rollup {
+	return &Rollup{Address: address, caller: caller}
+}
+
+// Operator is an entry of the operators mapping.
+type Operator struct {
+	Stake              *big.Int
+	Penalties          *big.Int
+	SuccessfulDisputes *big.Int
+}
+
+// PackRegisterAsAnOperator packs a registerAsAnOperator call. The stake is
+// sent as the transaction value.
+func PackRegisterAsAnOperator() []byte {
+	return pack("registerAsAnOperator")
+}
+
+// PackAddNewReceipt packs an addNewReceipt call posting the two matrices to
+// multiply.
+func PackAddNewReceipt(matrix1, matrix2 [3][3]*big.Int) ([]byte, error) {
+	return packChecked("addNewReceipt", matrix1, matrix2)
+}
+
+// PackSubmitResult packs a submitResult call.
+func PackSubmitResult(root [32]byte, results [3][3]*big.Int, requestId *big.Int) ([]byte, error) {
+	return packChecked("submitResult", root, results, requestId)
+}
+
+// UnpackSubmitResult decodes the arguments of submitResult calldata.
+func UnpackSubmitResult(input []byte) (root [32]byte, results [3][3]*big.Int, requestId *big.Int, err error) {
+	method := ABI.Methods["submitResult"]
+	if len(input) < 4 || string(input[:4]) != string(method.ID) {
+		return root, results, nil, fmt.Errorf("calldata is not a submitResult call")
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return root, results, nil, fmt.Errorf("failed to unpack submitResult arguments: %v", err)
+	}
+	return args[0].([32]byte), args[1].([3][3]*big.Int), args[2].(*big.Int), nil
+}
+
+// PackRaiseDispute packs a raiseDispute call against the result of
+// requestId.
+func PackRaiseDispute(requestId *big.Int) ([]byte, error) {
+	return packChecked("raiseDispute", requestId)
+}
+
+// GetMatrices returns the two matrices of receipt receiptId.
+func (r *Rollup) GetMatrices(opts *CallOpts, receiptId *big.Int) ([3][3]*big.Int, [3][3]*big.Int, error) {
+	var matrix1, matrix2 [3][3]*big.Int
+	out, err := r.call(opts, "getMatrices", receiptId)
+	if err != nil {
+		return matrix1, matrix2, err
+	}
+	matrix1 = *abi.ConvertType(out[0], new([3][3]*big.Int)).(*[3][3]*big.Int)
+	matrix2 = *abi.ConvertType(out[1], new([3][3]*big.Int)).(*[3][3]*big.Int)
+	return matrix1, matrix2, nil
+}
+
+// MerkleTreeRoot returns the root the contract computes over values.
+func (r *Rollup) MerkleTreeRoot(opts *CallOpts, values [9]*big.Int) ([32]byte, error) {
+	out, err := r.call(opts, "merkleTreeRoot", values)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return *abi.ConvertType(out[0], new([32]byte)).(*[32]byte), nil
+}
+
+// Operators returns the stake and record of operator.
+func (r *Rollup) Operators(opts *CallOpts, operator common.Address) (Operator, error) {
+	out, err := r.call(opts, "operators", operator)
+	if err != nil {
+		return Operator{}, err
+	}
+	return Operator{
+		Stake:              *abi.ConvertType(out[0], new(*big.Int)).(**big.Int),
+		Penalties:          *abi.ConvertType(out[1], new(*big.Int)).(**big.Int),
+		SuccessfulDisputes: *abi.ConvertType(out[2], new(*big.Int)).(**big.Int),
+	}, nil
+}
+
+// ChallengePeriod returns CHALLENGE_PERIOD.
+func (r *Rollup) ChallengePeriod(opts *CallOpts) (*big.Int, error) {
+	out, err := r.call(opts, "CHALLENGE_PERIOD")
+	if err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
+
+func (r *Rollup) call(opts *CallOpts, method string, args ...interface{}) ([]interface{}, error) {
+	if opts == nil {
+		opts = new(CallOpts)
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	input, err := packChecked(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	output, err := r.caller.CallContract(ctx, ethereum.CallMsg{From: opts.From, To: &r.Address, Data: input}, opts.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %v", method, err)
+	}
+	out, err := ABI.Unpack(method, output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack response from %s: %v", method, err)
+	}
+	return out, nil
+}
+
+func packChecked(method string, args ...interface{}) ([]byte, error) {
+	input, err := ABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack call data for %s: %v", method, err)
+	}
+	return input, nil
+}
+
+// pack is for methods whose arguments cannot fail to pack.
+func pack(method string, args ...interface{}) []byte {
+	input, err := packChecked(method, args...)
+	if err != nil {
+		panic(err)
+	}
+	return input
+}
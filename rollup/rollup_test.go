@@ -0,0 +1,164 @@
+package rollup
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The canonical signatures the hand-written bindings are built against. A
+// change to abi.json that adds, drops or alters one fails these tests until
+// the bindings are updated.
+var (
+	methodSignatures = map[string]string{
+		"CHALLENGE_PERIOD":     "CHALLENGE_PERIOD()",
+		"addNewReceipt":        "addNewReceipt(uint256[3][3],uint256[3][3])",
+		"getMatrices":          "getMatrices(uint256)",
+		"merkleTreeRoot":       "merkleTreeRoot(uint256[9])",
+		"operators":            "operators(address)",
+		"raiseDispute":         "raiseDispute(uint256)",
+		"registerAsAnOperator": "registerAsAnOperator()",
+		"submitResult":         "submitResult(bytes32,uint256[3][3],uint256)",
+	}
+	eventSignatures = map[common.Hash]string{
+		DisputeRaisedID:      "DisputeRaised(address,uint256)",
+		NewReceiptID:         "NewReceipt(address,uint256)",
+		OperatorRegisteredID: "OperatorRegistered(address,uint256)",
+		PenaltyAppliedID:     "PenaltyApplied(address,uint256)",
+		ResultSubmittedID:    "ResultSubmitted(address,uint256,bytes32)",
+		SuccessfulDisputeID:  "SuccessfulDispute(address)",
+	}
+)
+
+func TestMethodIDs(t *testing.T) {
+	if len(ABI.Methods) != len(methodSignatures) {
+		t.Errorf("ABI has %d methods, bindings cover %d", len(ABI.Methods), len(methodSignatures))
+	}
+	for name, sig := range methodSignatures {
+		method, ok := ABI.Methods[name]
+		if !ok {
+			t.Errorf("ABI has no method %s", name)
+			continue
+		}
+		if method.Sig != sig {
+			t.Errorf("method %s is %s, bindings expect %s", name, method.Sig, sig)
+		}
+		if want := crypto.Keccak256([]byte(sig))[:4]; !bytes.Equal(method.ID, want) {
+			t.Errorf("method %s has ID %x, want %x", name, method.ID, want)
+		}
+	}
+}
+
+func TestEventIDs(t *testing.T) {
+	if len(ABI.Events) != len(eventSignatures) {
+		t.Errorf("ABI has %d events, bindings cover %d", len(ABI.Events), len(eventSignatures))
+	}
+	for id, sig := range eventSignatures {
+		if want := crypto.Keccak256Hash([]byte(sig)); id != want {
+			t.Errorf("event %s has ID %s, want %s", sig, id.Hex(), want.Hex())
+		}
+		event, err := ABI.EventByID(id)
+		if err != nil {
+			t.Errorf("ABI has no event %s: %v", sig, err)
+			continue
+		}
+		if event.Sig != sig {
+			t.Errorf("event %s is %s in the ABI", sig, event.Sig)
+		}
+	}
+}
+
+// newLog encodes an event the way the contract would log it.
+func newLog(t *testing.T, name string, args ...interface{}) types.Log {
+	event := ABI.Events[name]
+	var (
+		indexed [][]interface{}
+		data    []interface{}
+	)
+	for i, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, []interface{}{args[i]})
+		} else {
+			data = append(data, args[i])
+		}
+	}
+	topics, err := abi.MakeTopics(indexed...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := event.Inputs.NonIndexed().Pack(data...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := types.Log{Topics: []common.Hash{event.ID}, Data: packed, BlockNumber: 7, Index: 2}
+	for _, topic := range topics {
+		log.Topics = append(log.Topics, topic[0])
+	}
+	return log
+}
+
+func TestDecodeEvent(t *testing.T) {
+	addr := common.HexToAddress("0xF5106D4ef61cd0a04a345495f59f536bB7cd6074")
+	meta := Meta{Block: 7, LogIndex: 2}
+	tests := []struct {
+		log  types.Log
+		want Event
+	}{
+		{newLog(t, "DisputeRaised", addr, big.NewInt(3)), &DisputeRaised{Disputer: addr, RequestId: big.NewInt(3), Meta: meta}},
+		{newLog(t, "NewReceipt", addr, big.NewInt(4)), &NewReceipt{Sender: addr, RequestId: big.NewInt(4), Meta: meta}},
+		{newLog(t, "OperatorRegistered", addr, big.NewInt(5)), &OperatorRegistered{Operator: addr, StakeAmount: big.NewInt(5), Meta: meta}},
+		{newLog(t, "PenaltyApplied", addr, big.NewInt(6)), &PenaltyApplied{Solver: addr, Penalties: big.NewInt(6), Meta: meta}},
+		{newLog(t, "ResultSubmitted", addr, big.NewInt(7), [32]byte{8}), &ResultSubmitted{Solver: addr, RequestId: big.NewInt(7), ResultRoot: [32]byte{8}, Meta: meta}},
+		{newLog(t, "SuccessfulDispute", addr), &SuccessfulDispute{Disputer: addr, Meta: meta}},
+	}
+	if len(tests) != len(ABI.Events) {
+		t.Fatalf("testing %d of %d events", len(tests), len(ABI.Events))
+	}
+	for _, tt := range tests {
+		t.Run(tt.want.EventName(), func(t *testing.T) {
+			got, err := DecodeEvent(tt.log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("decoded %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitResultRoundTrip(t *testing.T) {
+	var results [3][3]*big.Int
+	for i := range results {
+		for j := range results[i] {
+			results[i][j] = big.NewInt(int64(i*3 + j))
+		}
+	}
+	input, err := PackSubmitResult([32]byte{1}, results, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, gotResults, requestId, err := UnpackSubmitResult(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != ([32]byte{1}) || requestId.Int64() != 10 {
+		t.Fatalf("unpacked root %x and request %v", root, requestId)
+	}
+	for i := range results {
+		for j := range results[i] {
+			if gotResults[i][j].Cmp(results[i][j]) != 0 {
+				t.Fatalf("unpacked results %v, want %v", gotResults, results)
+			}
+		}
+	}
+	if _, _, _, err := UnpackSubmitResult(PackRegisterAsAnOperator()); err == nil {
+		t.Fatal("unpacked registerAsAnOperator calldata as submitResult")
+	}
+}
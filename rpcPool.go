@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// chainClient is the part of ethclient.Client this tool uses. rpcPool
+// implements it on top of several endpoints.
+type chainClient interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	NetworkID(ctx context.Context) (*big.Int, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+type rpcPoolConfig struct {
+	HealthInterval time.Duration // how often every endpoint is probed
+	ProbeTimeout   time.Duration
+	MaxLag         uint64  // blocks an endpoint may trail the best head and stay healthy
+	Smoothing      float64 // weight of the newest sample in the latency and error rate averages
+}
+
+func defaultRPCPoolConfig() rpcPoolConfig {
+	return rpcPoolConfig{
+		HealthInterval: 10 * time.Second,
+		ProbeTimeout:   5 * time.Second,
+		MaxLag:         2,
+		Smoothing:      0.2,
+	}
+}
+
+// endpointStats is a snapshot of how one endpoint has been behaving.
+type endpointStats struct {
+	URL       string
+	Healthy   bool
+	Head      uint64
+	Requests  uint64
+	Errors    uint64
+	Latency   time.Duration // moving average
+	ErrorRate float64       // moving average, 0 to 1
+	LastError error
+}
+
+type rpcEndpoint struct {
+	client *ethclient.Client
+
+	mu    sync.Mutex
+	stats endpointStats
+}
+
+// record folds the outcome of one request into the endpoint's stats. Errors
+// the node answered with, such as reverts, say nothing about its health and
+// are not counted.
+func (e *rpcEndpoint) record(cfg rpcPoolConfig, took time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	failed := err != nil && !isNodeAnswer(err)
+	e.stats.Requests++
+	sample := 0.0
+	if failed {
+		e.stats.Errors++
+		e.stats.LastError = err
+		sample = 1
+	}
+	if e.stats.Requests == 1 {
+		e.stats.Latency, e.stats.ErrorRate = took, sample
+		return
+	}
+	e.stats.Latency = time.Duration(cfg.Smoothing*float64(took) + (1-cfg.Smoothing)*float64(e.stats.Latency))
+	e.stats.ErrorRate = cfg.Smoothing*sample + (1-cfg.Smoothing)*e.stats.ErrorRate
+}
+
+func (e *rpcEndpoint) snapshot() endpointStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stats
+}
+
+// score orders healthy endpoints; lower is better. Recent errors weigh as
+// much as several times the latency.
+func (s endpointStats) score() float64 {
+	return float64(s.Latency) * (1 + 4*s.ErrorRate)
+}
+
+// rpcPool spreads work over several RPC endpoints. Reads go to the healthiest
+// endpoint and fail over to the next on transport errors; signed
+// transactions are broadcast to every endpoint.
+type rpcPool struct {
+	endpoints []*rpcEndpoint
+	cfg       rpcPoolConfig
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// dialRPCPool connects to every url and starts health checking. Endpoints
+// that cannot be dialed are left out; at least one must work.
+func dialRPCPool(ctx context.Context, urls []string, cfg rpcPoolConfig) (*rpcPool, error) {
+	p := &rpcPool{cfg: cfg, stop: make(chan struct{}), done: make(chan struct{})}
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			log.Printf("Failed to connect to %s: %v", url, err)
+			continue
+		}
+		p.endpoints = append(p.endpoints, &rpcEndpoint{client: client, stats: endpointStats{URL: url, Healthy: true}})
+	}
+	if len(p.endpoints) == 0 {
+		return nil, fmt.Errorf("failed to connect to any of %d RPC endpoints", len(urls))
+	}
+	p.checkHealth(ctx)
+	go p.healthLoop()
+	return p, nil
+}
+
+func (p *rpcPool) Close() {
+	close(p.stop)
+	<-p.done
+	for _, e := range p.endpoints {
+		e.client.Close()
+	}
+}
+
+func (p *rpcPool) healthLoop() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkHealth(context.Background())
+		}
+	}
+}
+
+// checkHealth probes every endpoint for its head. Endpoints that fail or lag
+// behind the best head by more than MaxLag blocks are marked unhealthy.
+func (p *rpcPool) checkHealth(ctx context.Context) {
+	heads := make([]uint64, len(p.endpoints))
+	errs := make([]error, len(p.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range p.endpoints {
+		wg.Add(1)
+		go func(i int, e *rpcEndpoint) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, p.cfg.ProbeTimeout)
+			defer cancel()
+			start := time.Now()
+			heads[i], errs[i] = e.client.BlockNumber(ctx)
+			e.record(p.cfg, time.Since(start), errs[i])
+		}(i, e)
+	}
+	wg.Wait()
+
+	var best uint64
+	for i := range heads {
+		if errs[i] == nil && heads[i] > best {
+			best = heads[i]
+		}
+	}
+	for i, e := range p.endpoints {
+		healthy := errs[i] == nil && heads[i]+p.cfg.MaxLag >= best
+		e.mu.Lock()
+		if healthy != e.stats.Healthy {
+			if healthy {
+				log.Printf("RPC endpoint %s is healthy again at block %d", e.stats.URL, heads[i])
+			} else if errs[i] != nil {
+				log.Printf("RPC endpoint %s is unhealthy: %v", e.stats.URL, errs[i])
+			} else {
+				log.Printf("RPC endpoint %s is unhealthy: at block %d, %d behind", e.stats.URL, heads[i], best-heads[i])
+			}
+		}
+		e.stats.Healthy = healthy
+		if errs[i] == nil {
+			e.stats.Head = heads[i]
+		}
+		e.mu.Unlock()
+	}
+}
+
+// ranked returns the endpoints best first: healthy ones by score, then the
+// rest so a read can still be tried when every endpoint looks down.
+func (p *rpcPool) ranked() []*rpcEndpoint {
+	type entry struct {
+		e     *rpcEndpoint
+		stats endpointStats
+	}
+	entries := make([]entry, len(p.endpoints))
+	for i, e := range p.endpoints {
+		entries[i] = entry{e, e.snapshot()}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].stats.Healthy != entries[j].stats.Healthy {
+			return entries[i].stats.Healthy
+		}
+		return entries[i].stats.score() < entries[j].stats.score()
+	})
+	ranked := make([]*rpcEndpoint, len(entries))
+	for i := range entries {
+		ranked[i] = entries[i].e
+	}
+	return ranked
+}
+
+// Stats returns a snapshot of every endpoint's stats.
+func (p *rpcPool) Stats() []endpointStats {
+	stats := make([]endpointStats, len(p.endpoints))
+	for i, e := range p.endpoints {
+		stats[i] = e.snapshot()
+	}
+	return stats
+}
+
+func (p *rpcPool) logStats() {
+	for _, s := range p.Stats() {
+		log.Printf("RPC endpoint %s: healthy= %v head= %d requests= %d errors= %d latency= %v errorRate= %.2f",
+			s.URL, s.Healthy, s.Head, s.Requests, s.Errors, s.Latency.Round(time.Millisecond), s.ErrorRate)
+	}
+}
+
+// read runs call against the best endpoint, moving on to the next one on
+// errors that are not answers from the node.
+func (p *rpcPool) read(ctx context.Context, call func(*ethclient.Client) error) error {
+	var err error
+	for _, e := range p.ranked() {
+		start := time.Now()
+		err = call(e.client)
+		e.record(p.cfg, time.Since(start), err)
+		if err == nil || isNodeAnswer(err) || ctx.Err() != nil {
+			return err
+		}
+		log.Printf("RPC endpoint %s failed, trying the next: %v", e.snapshot().URL, err)
+	}
+	return err
+}
+
+// receiptPinned fetches the receipt of hash and returns it with the client of
+// the endpoint that served it. Checks that must agree with the receipt, such
+// as whether its block is still canonical, go through that client so an
+// endpoint lagging behind cannot pass for a reorg. The client stays owned by
+// the pool and must not be closed.
+func (p *rpcPool) receiptPinned(ctx context.Context, hash common.Hash) (*types.Receipt, chainClient, error) {
+	var (
+		receipt *types.Receipt
+		served  *ethclient.Client
+	)
+	err := p.read(ctx, func(c *ethclient.Client) (err error) {
+		receipt, err = c.TransactionReceipt(ctx, hash)
+		served = c
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return receipt, served, nil
+}
+
+// isNodeAnswer reports whether err is a response from the node rather than a
+// failure to get one. A rate limit is not an answer: another endpoint may
+// well serve the request.
+func isNodeAnswer(err error) bool {
+	var rpcErr rpc.Error
+	if isRateLimited(err) {
+		return false
+	}
+	return errors.Is(err, ethereum.NotFound) || errors.As(err, &rpcErr)
+}
+
+// isRateLimited reports whether an endpoint refused a request for exceeding
+// its rate limit: HTTP 429, or the JSON-RPC "limit exceeded" code of EIP-1474
+// that Infura and others use. Some providers also put 429 in the JSON-RPC
+// error itself.
+func isRateLimited(err error) bool {
+	var (
+		httpErr rpc.HTTPError
+		rpcErr  rpc.Error
+	)
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == -32005 || rpcErr.ErrorCode() == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// SendTransaction broadcasts tx to every endpoint at once so a provider that
+// drops blob transactions cannot stall the submission. It succeeds if any
+// endpoint accepted tx or already knew it.
+func (p *rpcPool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	errs := make([]error, len(p.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range p.endpoints {
+		wg.Add(1)
+		go func(i int, e *rpcEndpoint) {
+			defer wg.Done()
+			start := time.Now()
+			errs[i] = e.client.SendTransaction(ctx, tx)
+			e.record(p.cfg, time.Since(start), errs[i])
+		}(i, e)
+	}
+	wg.Wait()
+
+	accepted := 0
+	var failures []string
+	for i, err := range errs {
+		if err == nil || isKnownTxError(err) {
+			accepted++
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", p.endpoints[i].snapshot().URL, err))
+	}
+	if accepted == 0 {
+		return fmt.Errorf("no endpoint accepted %s: %s", tx.Hash().Hex(), strings.Join(failures, "; "))
+	}
+	for _, f := range failures {
+		log.Printf("Broadcast of %s rejected by %s", tx.Hash().Hex(), f)
+	}
+	return nil
+}
+
+func (p *rpcPool) BlockNumber(ctx context.Context) (n uint64, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		n, err = c.BlockNumber(ctx)
+		return err
+	})
+	return n, err
+}
+
+func (p *rpcPool) HeaderByNumber(ctx context.Context, number *big.Int) (h *types.Header, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		h, err = c.HeaderByNumber(ctx, number)
+		return err
+	})
+	return h, err
+}
+
+func (p *rpcPool) HeaderByHash(ctx context.Context, hash common.Hash) (h *types.Header, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		h, err = c.HeaderByHash(ctx, hash)
+		return err
+	})
+	return h, err
+}
+
+func (p *rpcPool) NetworkID(ctx context.Context) (id *big.Int, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		id, err = c.NetworkID(ctx)
+		return err
+	})
+	return id, err
+}
+
+func (p *rpcPool) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, pending bool, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		tx, pending, err = c.TransactionByHash(ctx, hash)
+		return err
+	})
+	return tx, pending, err
+}
+
+func (p *rpcPool) TransactionReceipt(ctx context.Context, txHash common.Hash) (r *types.Receipt, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		r, err = c.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return r, err
+}
+
+func (p *rpcPool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (n uint64, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		n, err = c.NonceAt(ctx, account, blockNumber)
+		return err
+	})
+	return n, err
+}
+
+func (p *rpcPool) PendingNonceAt(ctx context.Context, account common.Address) (n uint64, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		n, err = c.PendingNonceAt(ctx, account)
+		return err
+	})
+	return n, err
+}
+
+func (p *rpcPool) SuggestGasTipCap(ctx context.Context) (tip *big.Int, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		tip, err = c.SuggestGasTipCap(ctx)
+		return err
+	})
+	return tip, err
+}
+
+func (p *rpcPool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) (code []byte, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		code, err = c.CodeAt(ctx, account, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+func (p *rpcPool) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (out []byte, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		out, err = c.CallContract(ctx, msg, blockNumber)
+		return err
+	})
+	return out, err
+}
+
+func (p *rpcPool) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (gas uint64, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		gas, err = c.EstimateGas(ctx, msg)
+		return err
+	})
+	return gas, err
+}
+
+func (p *rpcPool) FilterLogs(ctx context.Context, q ethereum.FilterQuery) (logs []types.Log, err error) {
+	err = p.read(ctx, func(c *ethclient.Client) error {
+		logs, err = c.FilterLogs(ctx, q)
+		return err
+	})
+	return logs, err
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// chainService is an eth namespace answering receipt and header lookups with
+// blocks tagged by name, so a test can tell which node served them.
+type chainService struct {
+	name    string
+	receipt bool
+	head    uint64
+	err     error // returned by eth_blockNumber and eth_sendRawTransaction
+	sends   int
+}
+
+// codedError is a JSON-RPC error with the given code.
+type codedError struct {
+	code int
+	msg  string
+}
+
+func (e *codedError) Error() string  { return e.msg }
+func (e *codedError) ErrorCode() int { return e.code }
+
+func (s *chainService) BlockNumber() (hexutil.Uint64, error) {
+	return hexutil.Uint64(s.head), s.err
+}
+
+func (s *chainService) SendRawTransaction(raw hexutil.Bytes) (common.Hash, error) {
+	s.sends++
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), s.err
+}
+
+func (s *chainService) header(number uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(number), Difficulty: new(big.Int), Extra: []byte(s.name)}
+}
+
+func (s *chainService) GetTransactionReceipt(hash common.Hash) (*types.Receipt, error) {
+	if !s.receipt {
+		return nil, nil
+	}
+	return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}, BlockNumber: big.NewInt(10), BlockHash: s.header(10).Hash()}, nil
+}
+
+func (s *chainService) GetBlockByNumber(number rpc.BlockNumber, full bool) (*types.Header, error) {
+	return s.header(uint64(number)), nil
+}
+
+func newTestEndpoint(t *testing.T, service *chainService, healthy bool) *rpcEndpoint {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	client := ethclient.NewClient(rpc.DialInProc(server))
+	t.Cleanup(client.Close)
+	return &rpcEndpoint{client: client, stats: endpointStats{URL: service.name, Healthy: healthy}}
+}
+
+func TestRPCPoolReceiptPinned(t *testing.T) {
+	lagging := newTestEndpoint(t, &chainService{name: "lagging"}, false)
+	synced := newTestEndpoint(t, &chainService{name: "synced", receipt: true}, true)
+	pool := &rpcPool{endpoints: []*rpcEndpoint{lagging, synced}, cfg: defaultRPCPoolConfig()}
+
+	receipt, pinned, err := pool.receiptPinned(context.Background(), common.Hash{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lagging node now ranks first, yet the receipt's block must still
+	// be looked up where the receipt came from.
+	lagging.stats.Healthy, synced.stats.Healthy = true, false
+	header, err := pinned.HeaderByNumber(context.Background(), receipt.BlockNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Hash() != receipt.BlockHash {
+		t.Fatalf("header served by %q, receipt by the synced node", header.Extra)
+	}
+	if header, _ := pool.HeaderByNumber(context.Background(), receipt.BlockNumber); string(header.Extra) != "lagging" {
+		t.Fatalf("unpinned read served by %q", header.Extra)
+	}
+}
+
+// newHTTPEndpoint is an endpoint whose HTTP server answers every request
+// with status.
+func newHTTPEndpoint(t *testing.T, name string, status int) *rpcEndpoint {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(status), status)
+	}))
+	t.Cleanup(server.Close)
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return &rpcEndpoint{client: client, stats: endpointStats{URL: name, Healthy: true}}
+}
+
+func TestRPCPoolFailover(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    func(t *testing.T) *rpcEndpoint
+		failover bool
+	}{
+		{
+			name: "rate limit code",
+			first: func(t *testing.T) *rpcEndpoint {
+				return newTestEndpoint(t, &chainService{name: "first", head: 1, err: &codedError{-32005, "limit exceeded"}}, true)
+			},
+			failover: true,
+		},
+		{
+			name: "429 in the JSON-RPC error",
+			first: func(t *testing.T) *rpcEndpoint {
+				return newTestEndpoint(t, &chainService{name: "first", head: 1, err: &codedError{429, "too many requests"}}, true)
+			},
+			failover: true,
+		},
+		{
+			name:     "HTTP 429",
+			first:    func(t *testing.T) *rpcEndpoint { return newHTTPEndpoint(t, "first", http.StatusTooManyRequests) },
+			failover: true,
+		},
+		{
+			name:     "HTTP 502",
+			first:    func(t *testing.T) *rpcEndpoint { return newHTTPEndpoint(t, "first", http.StatusBadGateway) },
+			failover: true,
+		},
+		{
+			name: "node answer",
+			first: func(t *testing.T) *rpcEndpoint {
+				return newTestEndpoint(t, &chainService{name: "first", head: 1, err: &codedError{-32000, "header not found"}}, true)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := tt.first(t)
+			second := newTestEndpoint(t, &chainService{name: "second", head: 2}, true)
+			// Make sure the first endpoint ranks first.
+			first.stats.Requests, first.stats.Latency = 1, 1
+			second.stats.Requests, second.stats.Latency = 1, 2
+			pool := &rpcPool{endpoints: []*rpcEndpoint{first, second}, cfg: defaultRPCPoolConfig()}
+
+			head, err := pool.BlockNumber(context.Background())
+			if !tt.failover {
+				if err == nil {
+					t.Fatalf("got head %d, want the first node's error", head)
+				}
+				if second.stats.Requests != 1 {
+					t.Fatal("failed over on an answer from the node")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if head != 2 {
+				t.Fatalf("head %d, want the second node's", head)
+			}
+			if first.stats.Errors != 1 {
+				t.Fatalf("first endpoint counted %d errors, want 1", first.stats.Errors)
+			}
+		})
+	}
+}
+
+func TestRPCPoolSendTransaction(t *testing.T) {
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1)})
+
+	accepting := &chainService{name: "accepting"}
+	knowing := &chainService{name: "knowing", err: &codedError{-32000, "already known"}}
+	rejecting := &chainService{name: "rejecting", err: &codedError{-32000, "transaction type not supported"}}
+	pool := &rpcPool{
+		endpoints: []*rpcEndpoint{
+			newTestEndpoint(t, accepting, true),
+			newTestEndpoint(t, knowing, true),
+			newTestEndpoint(t, rejecting, false),
+			newHTTPEndpoint(t, "down", http.StatusServiceUnavailable),
+		},
+		cfg: defaultRPCPoolConfig(),
+	}
+	if err := pool.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	// Unhealthy endpoints get the transaction too.
+	for _, s := range []*chainService{accepting, knowing, rejecting} {
+		if s.sends != 1 {
+			t.Errorf("%s got the transaction %d times", s.name, s.sends)
+		}
+	}
+
+	pool.endpoints = pool.endpoints[2:]
+	err := pool.SendTransaction(context.Background(), tx)
+	if err == nil {
+		t.Fatal("broadcast succeeded with no endpoint accepting it")
+	}
+	for _, name := range []string{"rejecting", "down"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not name endpoint %s", err, name)
+		}
+	}
+}
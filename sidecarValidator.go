@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// txpoolLimits mirrors the checks the geth blob pool applies on admission.
+type txpoolLimits struct {
+	MaxBlobsPerTx int
+	MinTip        *big.Int // pool-wide minimum gas tip
+	MinBlobFeeCap *big.Int // minimum blob gas fee cap
+	MaxTxSize     int      // largest non-blob transaction the legacy pool accepts
+}
+
+func defaultTxpoolLimits() txpoolLimits {
+	return txpoolLimits{
+		MaxBlobsPerTx: cancunMaxBlobsPerTx,
+		MinTip:        big.NewInt(1),
+		MinBlobFeeCap: big.NewInt(params.BlobTxMinBlobGasprice),
+		MaxTxSize:     4 * 32 * 1024,
+	}
+}
+
+// sidecarIssue is one failed check. Blob is -1 for transaction-wide issues.
+type sidecarIssue struct {
+	Check string
+	Blob  int
+	Err   error
+}
+
+func (i sidecarIssue) String() string {
+	if i.Blob < 0 {
+		return fmt.Sprintf("%s: %v", i.Check, i.Err)
+	}
+	return fmt.Sprintf("%s (blob %d): %v", i.Check, i.Blob, i.Err)
+}
+
+// sidecarReport collects every problem found in a blob transaction so they
+// can be reported together rather than one RPC rejection at a time.
+type sidecarReport struct {
+	TxHash common.Hash
+	Issues []sidecarIssue
+}
+
+func (r *sidecarReport) add(check string, blob int, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, sidecarIssue{Check: check, Blob: blob, Err: fmt.Errorf(format, args...)})
+}
+
+func (r *sidecarReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *sidecarReport) Error() string {
+	lines := make([]string, 0, len(r.Issues)+1)
+	lines = append(lines, fmt.Sprintf("blob transaction %s failed %d check(s):", r.TxHash.Hex(), len(r.Issues)))
+	for _, issue := range r.Issues {
+		lines = append(lines, "  "+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validateSidecar runs the blob pool's admission checks locally.
+func validateSidecar(tx *types.Transaction, limits txpoolLimits) *sidecarReport {
+	report := &sidecarReport{TxHash: tx.Hash()}
+
+	if tx.Type() != types.BlobTxType {
+		report.add("type", -1, "transaction type %d is not a blob transaction", tx.Type())
+		return report
+	}
+	sidecar := tx.BlobTxSidecar()
+	if sidecar == nil {
+		report.add("sidecar", -1, "transaction has no sidecar")
+		return report
+	}
+
+	hashes := tx.BlobHashes()
+	if len(hashes) == 0 {
+		report.add("count", -1, "transaction carries no blobs")
+	}
+	if len(hashes) > limits.MaxBlobsPerTx {
+		report.add("count", -1, "%d blobs exceed the limit of %d", len(hashes), limits.MaxBlobsPerTx)
+	}
+	if len(sidecar.Blobs) != len(hashes) || len(sidecar.Commitments) != len(hashes) || len(sidecar.Proofs) != len(hashes) {
+		report.add("count", -1, "%d blob hashes, %d blobs, %d commitments, %d proofs",
+			len(hashes), len(sidecar.Blobs), len(sidecar.Commitments), len(sidecar.Proofs))
+	} else {
+		hasher := sha256.New()
+		for i := range sidecar.Blobs {
+			if vh := common.Hash(kzg4844.CalcBlobHashV1(hasher, &sidecar.Commitments[i])); vh != hashes[i] {
+				report.add("hash", i, "commitment hashes to %s, transaction lists %s", vh.Hex(), hashes[i].Hex())
+			}
+			if err := kzg4844.VerifyBlobProof(sidecar.Blobs[i], sidecar.Commitments[i], sidecar.Proofs[i]); err != nil {
+				report.add("proof", i, "%v", err)
+			}
+		}
+	}
+
+	if tx.GasFeeCapIntCmp(tx.GasTipCap()) < 0 {
+		report.add("fees", -1, "fee cap %v below tip cap %v", tx.GasFeeCap(), tx.GasTipCap())
+	}
+	if limits.MinTip != nil && tx.GasTipCapIntCmp(limits.MinTip) < 0 {
+		report.add("fees", -1, "tip cap %v below pool minimum %v", tx.GasTipCap(), limits.MinTip)
+	}
+	if limits.MinBlobFeeCap != nil && tx.BlobGasFeeCapIntCmp(limits.MinBlobFeeCap) < 0 {
+		report.add("fees", -1, "blob fee cap %v below minimum %v", tx.BlobGasFeeCap(), limits.MinBlobFeeCap)
+	}
+	return report
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+
+	"blob/encoder"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+)
+
+func newValidatorTestTx(hashes []common.Hash, sidecar *types.BlobTxSidecar) *types.Transaction {
+	return types.NewTx(&types.BlobTx{
+		ChainID:    uint256.NewInt(1),
+		GasTipCap:  uint256.NewInt(1e9),
+		GasFeeCap:  uint256.NewInt(3e10),
+		Gas:        100000,
+		BlobFeeCap: uint256.NewInt(1e9),
+		BlobHashes: hashes,
+		Sidecar:    sidecar,
+	})
+}
+
+func TestValidateSidecar(t *testing.T) {
+	blobs, err := encoder.Encode(make([]byte, encoder.Packing31.Capacity()+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := encoder.NewSidecar(blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// copySidecar returns a sidecar that can be changed without touching valid.
+	copySidecar := func() *types.BlobTxSidecar {
+		return &types.BlobTxSidecar{
+			Blobs:       append([]kzg4844.Blob{}, valid.Blobs...),
+			Commitments: append([]kzg4844.Commitment{}, valid.Commitments...),
+			Proofs:      append([]kzg4844.Proof{}, valid.Proofs...),
+		}
+	}
+
+	type issue struct {
+		check string
+		blob  int
+	}
+	tests := []struct {
+		name   string
+		tx     func() *types.Transaction
+		limits func(*txpoolLimits)
+		want   []issue
+	}{
+		{
+			name: "valid",
+			tx:   func() *types.Transaction { return newValidatorTestTx(valid.BlobHashes(), valid) },
+		},
+		{
+			name: "wrong versioned hash",
+			tx: func() *types.Transaction {
+				hashes := valid.BlobHashes()
+				hashes[1][31] ^= 1
+				return newValidatorTestTx(hashes, valid)
+			},
+			want: []issue{{"hash", 1}},
+		},
+		{
+			name: "bad proof",
+			tx: func() *types.Transaction {
+				sidecar := copySidecar()
+				sidecar.Proofs[0] = sidecar.Proofs[1]
+				return newValidatorTestTx(valid.BlobHashes(), sidecar)
+			},
+			want: []issue{{"proof", 0}},
+		},
+		{
+			name: "blob count mismatch",
+			tx: func() *types.Transaction {
+				sidecar := copySidecar()
+				sidecar.Blobs = sidecar.Blobs[:1]
+				return newValidatorTestTx(valid.BlobHashes(), sidecar)
+			},
+			want: []issue{{"count", -1}},
+		},
+		{
+			name:   "over the blob limit",
+			tx:     func() *types.Transaction { return newValidatorTestTx(valid.BlobHashes(), valid) },
+			limits: func(l *txpoolLimits) { l.MaxBlobsPerTx = 1 },
+			want:   []issue{{"count", -1}},
+		},
+		{
+			name: "no sidecar",
+			tx:   func() *types.Transaction { return newValidatorTestTx(valid.BlobHashes(), nil) },
+			want: []issue{{"sidecar", -1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := defaultTxpoolLimits()
+			if tt.limits != nil {
+				tt.limits(&limits)
+			}
+			report := validateSidecar(tt.tx(), limits)
+			if report.OK() != (len(tt.want) == 0) {
+				t.Fatalf("OK() = %v with issues %v", report.OK(), report.Issues)
+			}
+			if len(report.Issues) != len(tt.want) {
+				t.Fatalf("got issues %v, want %v", report.Issues, tt.want)
+			}
+			for i, want := range tt.want {
+				if got := report.Issues[i]; got.Check != want.check || got.Blob != want.blob {
+					t.Errorf("issue %d is %v, want %s on blob %d", i, got, want.check, want.blob)
+				}
+			}
+		})
+	}
+}
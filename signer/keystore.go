@@ -0,0 +1,51 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// OpenKeystore decrypts a Web3 Secret Storage key file with passphrase.
+func OpenKeystore(path, passphrase string) (*Key, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %v", err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore file %s: %v", path, err)
+	}
+	return NewKey(key.PrivateKey), nil
+}
+
+// WriteKeystore encrypts privateKey with passphrase using the standard scrypt
+// parameters and writes it to path, which must not exist yet.
+func WriteKeystore(path string, privateKey *ecdsa.PrivateKey, passphrase string) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	key := &keystore.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+	keyJSON, err := keystore.EncryptKey(key, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt key: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(keyJSON); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,50 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := WriteKeystore(path, privateKey, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteKeystore(path, privateKey, "correct horse"); err == nil {
+		t.Fatal("overwrote an existing keystore file")
+	}
+	if _, err := OpenKeystore(path, "wrong horse"); err == nil {
+		t.Fatal("decrypted the keystore with the wrong passphrase")
+	}
+
+	key, err := OpenKeystore(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := crypto.PubkeyToAddress(privateKey.PublicKey)
+	if key.Address() != want {
+		t.Fatalf("keystore holds %s, want %s", key.Address().Hex(), want.Hex())
+	}
+
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000})
+	signedTx, err := key.SignTx(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := types.Sender(types.NewCancunSigner(tx.ChainId()), signedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender != want {
+		t.Fatalf("signature recovers to %s, want %s", sender.Hex(), want.Hex())
+	}
+}
@@ -0,0 +1,122 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TxArgs is the transaction argument of account_signTransaction. It follows
+// clef's SendTxArgs, including the blob fields newer signers accept. Blobs
+// themselves are not sent: the signature only covers their versioned hashes.
+type TxArgs struct {
+	From                 common.MixedcaseAddress  `json:"from"`
+	To                   *common.MixedcaseAddress `json:"to"`
+	Gas                  hexutil.Uint64           `json:"gas"`
+	MaxFeePerGas         *hexutil.Big             `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big             `json:"maxPriorityFeePerGas"`
+	Value                hexutil.Big              `json:"value"`
+	Nonce                hexutil.Uint64           `json:"nonce"`
+	Input                *hexutil.Bytes           `json:"input"`
+	AccessList           *types.AccessList        `json:"accessList,omitempty"`
+	ChainID              *hexutil.Big             `json:"chainId"`
+	BlobFeeCap           *hexutil.Big             `json:"maxFeePerBlobGas,omitempty"`
+	BlobHashes           []common.Hash            `json:"blobVersionedHashes,omitempty"`
+}
+
+// SignTxResponse is the result of account_signTransaction.
+type SignTxResponse struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// Remote signs through a JSON-RPC signer such as clef that serves
+// account_signTransaction.
+type Remote struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// DialRemote connects to the signer at url, which signs for address.
+func DialRemote(ctx context.Context, url string, address common.Address) (*Remote, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %v", err)
+	}
+	return &Remote{client: client, address: address}, nil
+}
+
+func (r *Remote) Address() common.Address {
+	return r.address
+}
+
+func (r *Remote) Close() {
+	r.client.Close()
+}
+
+// SignTx asks the remote signer for a signature over tx and applies it to tx
+// itself, so the sidecar is kept and a signer that signed anything else is
+// caught by the sender check.
+func (r *Remote) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	var res SignTxResponse
+	if err := r.client.CallContext(ctx, &res, "account_signTransaction", txArgs(r.address, tx)); err != nil {
+		return nil, fmt.Errorf("remote signer: %v", err)
+	}
+	remoteTx := new(types.Transaction)
+	if err := remoteTx.UnmarshalBinary(res.Raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned an undecodable transaction: %v", err)
+	}
+
+	signer := types.NewCancunSigner(tx.ChainId())
+	v, rr, s := remoteTx.RawSignatureValues()
+	if v.BitLen() > 1 {
+		return nil, fmt.Errorf("remote signer returned recovery id %v", v)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	rr.FillBytes(sig[:32])
+	s.FillBytes(sig[32:64])
+	sig[64] = byte(v.Uint64())
+
+	signedTx, err := tx.WithSignature(signer, sig)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := types.Sender(signer, signedTx)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid signature: %v", err)
+	}
+	if sender != r.address {
+		return nil, fmt.Errorf("remote signature recovers to %s instead of %s, the signed transaction differs", sender.Hex(), r.address.Hex())
+	}
+	return signedTx, nil
+}
+
+func txArgs(from common.Address, tx *types.Transaction) *TxArgs {
+	input := hexutil.Bytes(tx.Data())
+	accessList := tx.AccessList()
+	args := &TxArgs{
+		From:                 common.NewMixedcaseAddress(from),
+		Gas:                  hexutil.Uint64(tx.Gas()),
+		MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap()),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap()),
+		Value:                hexutil.Big(*tx.Value()),
+		Nonce:                hexutil.Uint64(tx.Nonce()),
+		Input:                &input,
+		AccessList:           &accessList,
+		ChainID:              (*hexutil.Big)(tx.ChainId()),
+	}
+	if to := tx.To(); to != nil {
+		addr := common.NewMixedcaseAddress(*to)
+		args.To = &addr
+	}
+	if tx.Type() == types.BlobTxType {
+		args.BlobFeeCap = (*hexutil.Big)(tx.BlobGasFeeCap())
+		args.BlobHashes = tx.BlobHashes()
+	}
+	return args
+}
@@ -0,0 +1,223 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+)
+
+// startStub serves account_signTransaction over HTTP with key held in
+// memory, standing in for clef, and returns its URL. It stops with the test.
+func startStub(t *testing.T, key *ecdsa.PrivateKey) string {
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", &stubAccountAPI{key: NewKey(key)}); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+	return httpServer.URL
+}
+
+type stubAccountAPI struct {
+	key *Key
+}
+
+// SignTransaction serves account_signTransaction.
+func (api *stubAccountAPI) SignTransaction(ctx context.Context, args TxArgs, methodSelector *string) (*SignTxResponse, error) {
+	if from := args.From.Address(); from != api.key.Address() {
+		return nil, fmt.Errorf("unknown account %s", from.Hex())
+	}
+	tx, err := args.toTransaction()
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := api.key.SignTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &SignTxResponse{Raw: raw, Tx: signedTx}, nil
+}
+
+// toTransaction rebuilds the unsigned transaction args describe, as a signer
+// does before signing it.
+func (args *TxArgs) toTransaction() (*types.Transaction, error) {
+	if args.MaxFeePerGas == nil || args.MaxPriorityFeePerGas == nil || args.ChainID == nil || args.Input == nil {
+		return nil, fmt.Errorf("only dynamic fee and blob transactions are supported")
+	}
+	var to *common.Address
+	if args.To != nil {
+		addr := args.To.Address()
+		to = &addr
+	}
+	var accessList types.AccessList
+	if args.AccessList != nil {
+		accessList = *args.AccessList
+	}
+	if args.BlobFeeCap == nil && len(args.BlobHashes) == 0 {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(args.Nonce),
+			GasTipCap:  (*big.Int)(args.MaxPriorityFeePerGas),
+			GasFeeCap:  (*big.Int)(args.MaxFeePerGas),
+			Gas:        uint64(args.Gas),
+			To:         to,
+			Value:      (*big.Int)(&args.Value),
+			Data:       *args.Input,
+			AccessList: accessList,
+		}), nil
+	}
+	if to == nil || args.BlobFeeCap == nil {
+		return nil, fmt.Errorf("blob transaction needs a recipient and maxFeePerBlobGas")
+	}
+	return types.NewTx(&types.BlobTx{
+		ChainID:    uint256.MustFromBig((*big.Int)(args.ChainID)),
+		Nonce:      uint64(args.Nonce),
+		GasTipCap:  uint256.MustFromBig((*big.Int)(args.MaxPriorityFeePerGas)),
+		GasFeeCap:  uint256.MustFromBig((*big.Int)(args.MaxFeePerGas)),
+		Gas:        uint64(args.Gas),
+		To:         *to,
+		Value:      uint256.MustFromBig((*big.Int)(&args.Value)),
+		Data:       *args.Input,
+		AccessList: accessList,
+		BlobFeeCap: uint256.MustFromBig((*big.Int)(args.BlobFeeCap)),
+		BlobHashes: args.BlobHashes,
+	}), nil
+}
+
+func TestRemoteSignsLikeKey(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := NewKey(key)
+	remote, err := DialRemote(context.Background(), startStub(t, key), local.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	to := common.HexToAddress("0xF5106D4ef61cd0a04a345495f59f536bB7cd6074")
+	tests := map[string]*types.Transaction{
+		"dynamic fee": types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(11155111),
+			Nonce:     3,
+			GasTipCap: big.NewInt(1e9),
+			GasFeeCap: big.NewInt(3e10),
+			Gas:       21000,
+			To:        &to,
+			Value:     big.NewInt(1),
+			Data:      []byte{0xca, 0xfe},
+		}),
+		"blob": types.NewTx(&types.BlobTx{
+			ChainID:    uint256.NewInt(11155111),
+			Nonce:      4,
+			GasTipCap:  uint256.NewInt(1e9),
+			GasFeeCap:  uint256.NewInt(3e10),
+			Gas:        100000,
+			To:         to,
+			Value:      new(uint256.Int),
+			Data:       []byte{0xca, 0xfe},
+			AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{1}}}},
+			BlobFeeCap: uint256.NewInt(3e10),
+			BlobHashes: []common.Hash{{0x01, 2}, {0x01, 3}},
+		}),
+	}
+	for name, tx := range tests {
+		t.Run(name, func(t *testing.T) {
+			want, err := local.SignTx(context.Background(), tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := remote.SignTx(context.Background(), tx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Hash() != want.Hash() {
+				t.Fatalf("remote signed %s, local %s", got.Hash().Hex(), want.Hash().Hex())
+			}
+		})
+	}
+}
+
+func TestRemoteRejectsOtherSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	remote, err := DialRemote(context.Background(), startStub(t, other), crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), Gas: 21000})
+	if _, err := remote.SignTx(context.Background(), tx); err == nil {
+		t.Fatal("signed for an account the signer does not hold")
+	}
+}
+
+func TestRemoteKeepsSidecar(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	remote, err := DialRemote(context.Background(), startStub(t, key), crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	var blob kzg4844.Blob
+	blob[1] = 0xaa
+	commitment, err := kzg4844.BlobToCommitment(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := kzg4844.ComputeBlobProof(blob, commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{blob},
+		Commitments: []kzg4844.Commitment{commitment},
+		Proofs:      []kzg4844.Proof{proof},
+	}
+	tx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.NewInt(11155111),
+		GasTipCap:  uint256.NewInt(1e9),
+		GasFeeCap:  uint256.NewInt(3e10),
+		Gas:        100000,
+		To:         common.HexToAddress("0xF5106D4ef61cd0a04a345495f59f536bB7cd6074"),
+		BlobFeeCap: uint256.NewInt(3e10),
+		BlobHashes: []common.Hash{kzg4844.CalcBlobHashV1(sha256.New(), &commitment)},
+		Sidecar:    sidecar,
+	})
+
+	signedTx, err := remote.SignTx(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := signedTx.BlobTxSidecar()
+	if got == nil {
+		t.Fatal("signed transaction lost its sidecar")
+	}
+	if got.Blobs[0] != blob || got.Commitments[0] != commitment || got.Proofs[0] != proof {
+		t.Fatal("signed transaction carries a different sidecar")
+	}
+	if sender, err := types.Sender(types.NewCancunSigner(tx.ChainId()), signedTx); err != nil || sender != remote.Address() {
+		t.Fatalf("signed by %s (%v), want %s", sender.Hex(), err, remote.Address().Hex())
+	}
+}
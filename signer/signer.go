@@ -0,0 +1,41 @@
+// Package signer signs transactions with keys held in memory, in an encrypted
+// keystore file or behind a remote signer.
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs transactions for one account.
+type Signer interface {
+	Address() common.Address
+	// SignTx returns a signed copy of tx, keeping its blob sidecar.
+	SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error)
+}
+
+// Key signs with a private key held in memory.
+type Key struct {
+	key *ecdsa.PrivateKey
+}
+
+func NewKey(key *ecdsa.PrivateKey) *Key {
+	return &Key{key: key}
+}
+
+func (k *Key) Address() common.Address {
+	return crypto.PubkeyToAddress(k.key.PublicKey)
+}
+
+func (k *Key) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	signedTx, err := types.SignTx(tx, types.NewCancunSigner(tx.ChainId()), k.key)
+	if err != nil {
+		return nil, fmt.Errorf("error signing transaction: %v", err)
+	}
+	return signedTx, nil
+}
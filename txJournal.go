@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type journalState string
+
+const (
+	journalSent      journalState = "sent"      // signed and handed to the node
+	journalReplaced  journalState = "replaced"  // superseded by a version with bumped fees
+	journalConfirmed journalState = "confirmed" // mined and buried under enough blocks
+	journalFailed    journalState = "failed"    // could not be sent, or reverted
+	journalDropped   journalState = "dropped"   // its nonce was used by another transaction
+)
+
+// journalRecord is one line of the journal. Sent records carry the network
+// encoding of the transaction, sidecar included, so it can be rebroadcast.
+type journalRecord struct {
+	Time       time.Time      `json:"time"`
+	State      journalState   `json:"state"`
+	TxHash     common.Hash    `json:"txHash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	RequestId  *hexutil.Big   `json:"requestId,omitempty"`
+	Raw        hexutil.Bytes  `json:"raw,omitempty"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+	Block      hexutil.Uint64 `json:"block,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// journalEntry is the latest known state of one journaled transaction.
+type journalEntry struct {
+	Tx        *types.Transaction
+	From      common.Address
+	RequestId *big.Int
+	State     journalState
+}
+
+// journalNonce is every version sent for one sender and nonce, oldest first.
+type journalNonce struct {
+	From      common.Address
+	Nonce     uint64
+	RequestId *big.Int
+	Versions  []*types.Transaction
+}
+
+// txJournal is an append-only log of every signed transaction and what
+// became of it. Replaying it after a restart tells which submissions are
+// still in flight.
+type txJournal struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries map[common.Hash]*journalEntry
+	order   []common.Hash
+}
+
+// openTxJournal replays the journal at path and opens it for appending. A
+// torn last line, left by a crash mid-write, is ignored.
+func openTxJournal(path string) (*txJournal, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transaction journal: %v", err)
+	}
+	j := &txJournal{file: file, entries: make(map[common.Hash]*journalEntry)}
+
+	reader := bufio.NewReader(file)
+	var valid int64
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("Ignoring torn last line %d of %s", lineNo, path)
+			}
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read transaction journal: %v", err)
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("line %d of %s: %v", lineNo, path, err)
+		}
+		if err := j.apply(&rec); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("line %d of %s: %v", lineNo, path, err)
+		}
+		valid += int64(len(line))
+	}
+	// Cut off the torn line so the next record starts on a line of its own.
+	if err := file.Truncate(valid); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *txJournal) Close() error {
+	return j.file.Close()
+}
+
+func (j *txJournal) apply(rec *journalRecord) error {
+	entry, ok := j.entries[rec.TxHash]
+	if !ok {
+		if rec.State != journalSent || len(rec.Raw) == 0 {
+			return fmt.Errorf("%s record for unknown transaction %s", rec.State, rec.TxHash.Hex())
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(rec.Raw); err != nil {
+			return fmt.Errorf("transaction %s: %v", rec.TxHash.Hex(), err)
+		}
+		if tx.Hash() != rec.TxHash {
+			return fmt.Errorf("transaction %s is stored with hash %s", tx.Hash().Hex(), rec.TxHash.Hex())
+		}
+		entry = &journalEntry{Tx: tx, From: rec.From, RequestId: (*big.Int)(rec.RequestId)}
+		j.entries[rec.TxHash] = entry
+		j.order = append(j.order, rec.TxHash)
+	}
+	entry.State = rec.State
+	return nil
+}
+
+// append writes rec and syncs it to disk before applying it.
+func (j *txJournal) append(rec *journalRecord) error {
+	rec.Time = time.Now().UTC()
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write transaction journal: %v", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync transaction journal: %v", err)
+	}
+	return j.apply(rec)
+}
+
+func (j *txJournal) record(state journalState, tx *types.Transaction) (*journalRecord, error) {
+	from, err := types.Sender(types.NewCancunSigner(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot journal transaction %s: %v", tx.Hash().Hex(), err)
+	}
+	return &journalRecord{State: state, TxHash: tx.Hash(), From: from, Nonce: hexutil.Uint64(tx.Nonce())}, nil
+}
+
+// Sent journals a signed transaction submitting the result of requestId.
+func (j *txJournal) Sent(signedTx *types.Transaction, requestId *big.Int) error {
+	rec, err := j.record(journalSent, signedTx)
+	if err != nil {
+		return err
+	}
+	if rec.Raw, err = signedTx.MarshalBinary(); err != nil {
+		return err
+	}
+	if requestId != nil {
+		rec.RequestId = (*hexutil.Big)(requestId)
+	}
+	return j.append(rec)
+}
+
+// Replaced journals that replacement superseded tx.
+func (j *txJournal) Replaced(tx, replacement *types.Transaction) error {
+	j.mu.Lock()
+	var requestId *big.Int
+	if entry, ok := j.entries[tx.Hash()]; ok {
+		requestId = entry.RequestId
+	}
+	j.mu.Unlock()
+
+	if err := j.Sent(replacement, requestId); err != nil {
+		return err
+	}
+	rec, err := j.record(journalReplaced, tx)
+	if err != nil {
+		return err
+	}
+	hash := replacement.Hash()
+	rec.ReplacedBy = &hash
+	return j.append(rec)
+}
+
+func (j *txJournal) Confirmed(tx *types.Transaction, block uint64) error {
+	rec, err := j.record(journalConfirmed, tx)
+	if err != nil {
+		return err
+	}
+	rec.Block = hexutil.Uint64(block)
+	return j.append(rec)
+}
+
+func (j *txJournal) Failed(tx *types.Transaction, cause error) error {
+	rec, err := j.record(journalFailed, tx)
+	if err != nil {
+		return err
+	}
+	rec.Error = cause.Error()
+	return j.append(rec)
+}
+
+func (j *txJournal) Dropped(tx *types.Transaction) error {
+	rec, err := j.record(journalDropped, tx)
+	if err != nil {
+		return err
+	}
+	return j.append(rec)
+}
+
+// Unfinished returns the nonces none of whose versions reached a final
+// state, in the order they were first sent.
+func (j *txJournal) Unfinished() []journalNonce {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	type key struct {
+		from  common.Address
+		nonce uint64
+	}
+	groups := make(map[key]*journalNonce)
+	finished := make(map[key]bool)
+	var keys []key
+	for _, hash := range j.order {
+		entry := j.entries[hash]
+		k := key{entry.From, entry.Tx.Nonce()}
+		switch entry.State {
+		case journalConfirmed, journalFailed, journalDropped:
+			finished[k] = true
+		}
+		g, ok := groups[k]
+		if !ok {
+			g = &journalNonce{From: entry.From, Nonce: entry.Tx.Nonce(), RequestId: entry.RequestId}
+			groups[k] = g
+			keys = append(keys, k)
+		}
+		g.Versions = append(g.Versions, entry.Tx)
+	}
+
+	var unfinished []journalNonce
+	for _, k := range keys {
+		if !finished[k] {
+			unfinished = append(unfinished, *groups[k])
+		}
+	}
+	return unfinished
+}
+
+// resumeJournal reconciles every unfinished journal entry with the chain: a
+// mined version is tracked to confirmation, a nonce taken by another
+// transaction is marked dropped, and anything else is rebroadcast and
+// followed like a fresh submission, with replacements if it gets stuck.
+func resumeJournal(ctx context.Context, client chainClient, journal *txJournal, replacer *replacementManager, cfg trackerConfig, archive *blobArchive, nonces *nonceManager) error {
+	for _, n := range journal.Unfinished() {
+		log.Printf("Resuming nonce %d of %s for request %v with %d version(s)", n.Nonce, n.From.Hex(), n.RequestId, len(n.Versions))
+		landedTx, err := reconcileNonce(ctx, client, journal, replacer, n)
+		if err != nil {
+			return err
+		}
+		if landedTx == nil {
+			continue
+		}
+
+		receipt, err := trackTransaction(ctx, client, landedTx, cfg)
+		if errors.Is(err, errNonceTaken) {
+			log.Print(err)
+			if err := journal.Dropped(landedTx); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			if receipt == nil {
+				return err
+			}
+			log.Print(err)
+			if err := journal.Failed(landedTx, err); err != nil {
+				return err
+			}
+		} else if err := journal.Confirmed(landedTx, receipt.BlockNumber.Uint64()); err != nil {
+			return err
+		}
+		if nonces != nil && n.From == nonces.Address() {
+			if err := nonces.Confirm(n.Nonce); err != nil {
+				log.Print(err)
+			}
+		}
+		if n.RequestId != nil && landedTx.BlobTxSidecar() != nil {
+			if err := archive.PutTransaction(landedTx, n.RequestId, receipt.BlockNumber.Uint64()); err != nil {
+				log.Printf("Failed to archive blobs of %s: %v", landedTx.Hash().Hex(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// reconcileNonce returns the version of n that made it into a block, or nil
+// if the nonce went to another transaction. A nonce that is still pending
+// can only be waited on by a replacer signing for its sender.
+func reconcileNonce(ctx context.Context, client chainClient, journal *txJournal, replacer *replacementManager, n journalNonce) (*types.Transaction, error) {
+	for _, tx := range n.Versions {
+		if _, err := client.TransactionReceipt(ctx, tx.Hash()); err == nil {
+			return tx, nil
+		}
+	}
+
+	latest := n.Versions[len(n.Versions)-1]
+	mined, err := client.NonceAt(ctx, n.From, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting nonce: %v", err)
+	}
+	if mined > n.Nonce {
+		log.Printf("Nonce %d of %s was used by a transaction not in the journal", n.Nonce, n.From.Hex())
+		return nil, journal.Dropped(latest)
+	}
+
+	if err := client.SendTransaction(ctx, latest); err != nil && !isKnownTxError(err) {
+		log.Printf("Failed to rebroadcast %s: %v", latest.Hash().Hex(), err)
+	}
+	if replacer == nil {
+		return latest, nil
+	}
+	if signer := replacer.signer.Address(); signer != n.From {
+		return nil, fmt.Errorf("nonce %d of %s is still pending but the signer is %s, which cannot replace it", n.Nonce, n.From.Hex(), signer.Hex())
+	}
+	return replacer.waitForInclusion(ctx, latest)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"blob/signer"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func newJournalTestTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, tip int64) *types.Transaction {
+	t.Helper()
+	tx, err := types.SignNewTx(key, types.NewCancunSigner(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(tip),
+		GasFeeCap: big.NewInt(3e10),
+		Gas:       21000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func openTestJournal(t *testing.T, path string) *txJournal {
+	t.Helper()
+	journal, err := openTxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { journal.Close() })
+	return journal
+}
+
+func TestTxJournalTruncatesTornLine(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	journal := openTestJournal(t, path)
+	tx := newJournalTestTx(t, key, 0, 1e9)
+	if err := journal.Sent(tx, big.NewInt(3)); err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+	valid, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash in the middle of a write leaves half a record behind.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-01-01T00:00:00Z","state":"conf`)
+	f.Close()
+
+	journal = openTestJournal(t, path)
+	if info, _ := os.Stat(path); info.Size() != valid.Size() {
+		t.Fatalf("journal is %d bytes after reopening, want %d", info.Size(), valid.Size())
+	}
+	if err := journal.Confirmed(tx, 5); err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+
+	journal = openTestJournal(t, path)
+	if entry := journal.entries[tx.Hash()]; entry == nil || entry.State != journalConfirmed || entry.RequestId.Int64() != 3 {
+		t.Fatalf("replayed entry %+v", entry)
+	}
+}
+
+func TestTxJournalApplyRejects(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx := newJournalTestTx(t, key, 0, 1e9)
+	raw, _ := tx.MarshalBinary()
+
+	tests := map[string]*journalRecord{
+		"hash mismatch":   {State: journalSent, TxHash: common.Hash{1}, Raw: raw},
+		"no transaction":  {State: journalSent, TxHash: tx.Hash()},
+		"unknown hash":    {State: journalConfirmed, TxHash: tx.Hash()},
+		"undecodable raw": {State: journalSent, TxHash: tx.Hash(), Raw: hexutil.Bytes{0x02, 0xff}},
+	}
+	for name, rec := range tests {
+		t.Run(name, func(t *testing.T) {
+			journal := &txJournal{entries: make(map[common.Hash]*journalEntry)}
+			if err := journal.apply(rec); err == nil {
+				t.Fatal("applied the record")
+			}
+			if len(journal.entries) != 0 {
+				t.Fatal("record left an entry behind")
+			}
+		})
+	}
+}
+
+func TestTxJournalUnfinished(t *testing.T) {
+	alice, _ := crypto.GenerateKey()
+	bob, _ := crypto.GenerateKey()
+	journal := openTestJournal(t, filepath.Join(t.TempDir(), "journal.jsonl"))
+
+	send := func(tx *types.Transaction) *types.Transaction {
+		if err := journal.Sent(tx, nil); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	// Both senders use nonce 0; they must not be grouped together.
+	a0 := send(newJournalTestTx(t, alice, 0, 1e9))
+	b0 := send(newJournalTestTx(t, bob, 0, 1e9))
+	a1 := send(newJournalTestTx(t, alice, 1, 1e9))
+	a1Bumped := newJournalTestTx(t, alice, 1, 2e9)
+	if err := journal.Replaced(a1, a1Bumped); err != nil {
+		t.Fatal(err)
+	}
+	a2 := send(newJournalTestTx(t, alice, 2, 1e9))
+	a3 := send(newJournalTestTx(t, alice, 3, 1e9))
+	a4 := send(newJournalTestTx(t, alice, 4, 1e9))
+
+	if err := journal.Confirmed(a2, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Failed(a3, os.ErrClosed); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Dropped(a4); err != nil {
+		t.Fatal(err)
+	}
+
+	got := journal.Unfinished()
+	want := []struct {
+		from     common.Address
+		nonce    uint64
+		versions []common.Hash
+	}{
+		{crypto.PubkeyToAddress(alice.PublicKey), 0, []common.Hash{a0.Hash()}},
+		{crypto.PubkeyToAddress(bob.PublicKey), 0, []common.Hash{b0.Hash()}},
+		{crypto.PubkeyToAddress(alice.PublicKey), 1, []common.Hash{a1.Hash(), a1Bumped.Hash()}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d unfinished nonces, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].From != w.from || got[i].Nonce != w.nonce || len(got[i].Versions) != len(w.versions) {
+			t.Fatalf("unfinished %d is nonce %d of %s with %d versions", i, got[i].Nonce, got[i].From.Hex(), len(got[i].Versions))
+		}
+		for j, hash := range w.versions {
+			if got[i].Versions[j].Hash() != hash {
+				t.Errorf("version %d of nonce %d is %s, want %s", j, w.nonce, got[i].Versions[j].Hash().Hex(), hash.Hex())
+			}
+		}
+	}
+}
+
+// minedNonceClient knows no receipts and reports a fixed mined nonce.
+type minedNonceClient struct {
+	chainClient
+	mined uint64
+	sends int
+}
+
+func (c *minedNonceClient) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	return nil, ethereum.NotFound
+}
+
+func (c *minedNonceClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return c.mined, nil
+}
+
+func (c *minedNonceClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.sends++
+	return nil
+}
+
+func TestReconcileNonceDropped(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	journal := openTestJournal(t, filepath.Join(t.TempDir(), "journal.jsonl"))
+	tx := newJournalTestTx(t, key, 4, 1e9)
+	if err := journal.Sent(tx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &minedNonceClient{mined: 5}
+	landedTx, err := reconcileNonce(context.Background(), client, journal, nil, journal.Unfinished()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if landedTx != nil || client.sends != 0 {
+		t.Fatalf("returned %v after %d rebroadcasts for a nonce taken by another transaction", landedTx, client.sends)
+	}
+	if state := journal.entries[tx.Hash()].State; state != journalDropped {
+		t.Fatalf("transaction is %s, want %s", state, journalDropped)
+	}
+	if unfinished := journal.Unfinished(); len(unfinished) != 0 {
+		t.Fatalf("%d nonces still unfinished", len(unfinished))
+	}
+}
+
+func TestReconcileNonceOtherSigner(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	journal := openTestJournal(t, filepath.Join(t.TempDir(), "journal.jsonl"))
+	if err := journal.Sent(newJournalTestTx(t, key, 4, 1e9), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &minedNonceClient{mined: 4}
+	replacer := &replacementManager{client: client, signer: signer.NewKey(other), cfg: defaultReplacementConfig()}
+	if _, err := reconcileNonce(context.Background(), client, journal, replacer, journal.Unfinished()[0]); err == nil {
+		t.Fatal("waited on a pending nonce the signer cannot replace")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"blob/signer"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+// blobPriceBump is the percentage by which the geth blob pool requires the
+// tip, fee cap and blob fee cap of a replacement to exceed the original.
+const blobPriceBump = 100
+
+type replacementConfig struct {
+	StuckAfter   uint64        // blocks without inclusion before replacing
+	PollInterval time.Duration // how often to check for inclusion
+	MaxSpend     *big.Int      // highest worst-case cost a replacement may commit to
+}
+
+func defaultReplacementConfig() replacementConfig {
+	return replacementConfig{
+		StuckAfter:   5,
+		PollInterval: 4 * time.Second,
+		MaxSpend:     big.NewInt(1e17),
+	}
+}
+
+// replacementManager keeps a blob transaction moving: while it stays pending
+// for too long, it is re-signed with the same nonce and sidecar and fees high
+// enough for the blob pool to accept the replacement.
+type replacementManager struct {
+	client  chainClient
+	signer  signer.Signer
+	cfg     replacementConfig
+	journal *txJournal // optional, records every replacement sent
+}
+
+// waitForInclusion returns whichever version of signedTx was mined. The
+// replacement that would exceed the configured spend is not sent; the
+// manager keeps waiting on the last version instead.
+func (m *replacementManager) waitForInclusion(ctx context.Context, signedTx *types.Transaction) (*types.Transaction, error) {
+	versions := []*types.Transaction{signedTx}
+	sentAt, err := m.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch head block number: %v", err)
+	}
+	capped := false
+
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		for _, tx := range versions {
+			if _, err := m.client.TransactionReceipt(ctx, tx.Hash()); err == nil {
+				return tx, nil
+			}
+		}
+
+		head, err := m.client.BlockNumber(ctx)
+		if err != nil {
+			log.Printf("Failed to fetch head block number: %v", err)
+		} else if !capped && head >= sentAt+m.cfg.StuckAfter {
+			latest := versions[len(versions)-1]
+			replacement, err := m.replace(ctx, latest)
+			switch {
+			case err == errMaxSpend:
+				log.Printf("Not replacing %s: bumped fees would exceed the maximum spend of %v wei", latest.Hash().Hex(), m.cfg.MaxSpend)
+				capped = true
+			case err != nil:
+				log.Printf("Failed to replace %s: %v", latest.Hash().Hex(), err)
+			default:
+				log.Printf("Replaced %s stuck for %d blocks with %s", latest.Hash().Hex(), head-sentAt, replacement.Hash().Hex())
+				versions = append(versions, replacement)
+				sentAt = head
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+var errMaxSpend = errors.New("replacement exceeds maximum spend")
+
+// replace signs and sends a bumped copy of tx.
+func (m *replacementManager) replace(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	bumped, err := bumpBlobTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	if m.cfg.MaxSpend != nil && bumped.Cost().Cmp(m.cfg.MaxSpend) > 0 {
+		return nil, errMaxSpend
+	}
+	signedTx, err := m.signer.SignTx(ctx, bumped)
+	if err != nil {
+		return nil, fmt.Errorf("error signing replacement: %v", err)
+	}
+	if err := m.client.SendTransaction(ctx, signedTx); err != nil {
+		return nil, fmt.Errorf("failed to send replacement: %v", err)
+	}
+	if m.journal != nil {
+		if err := m.journal.Replaced(tx, signedTx); err != nil {
+			log.Print(err)
+		}
+	}
+	return signedTx, nil
+}
+
+// bumpBlobTx returns an unsigned copy of tx with the same nonce, call and
+// sidecar, and every fee cap raised by blobPriceBump percent.
+func bumpBlobTx(tx *types.Transaction) (*types.Transaction, error) {
+	return copyBlobTx(tx, func(inner *types.BlobTx) {
+		inner.GasTipCap = bumpFee(tx.GasTipCap())
+		inner.GasFeeCap = bumpFee(tx.GasFeeCap())
+		inner.BlobFeeCap = bumpFee(tx.BlobGasFeeCap())
+	})
+}
+
+// copyBlobTx rebuilds the unsigned fields of a blob transaction, lets edit
+// change them and returns the new transaction.
+func copyBlobTx(tx *types.Transaction, edit func(*types.BlobTx)) (*types.Transaction, error) {
+	if tx.Type() != types.BlobTxType || tx.BlobTxSidecar() == nil {
+		return nil, fmt.Errorf("transaction %s is not a blob transaction with sidecar", tx.Hash().Hex())
+	}
+	inner := &types.BlobTx{
+		ChainID:    uint256.MustFromBig(tx.ChainId()),
+		Nonce:      tx.Nonce(),
+		GasTipCap:  uint256.MustFromBig(tx.GasTipCap()),
+		GasFeeCap:  uint256.MustFromBig(tx.GasFeeCap()),
+		Gas:        tx.Gas(),
+		To:         *tx.To(),
+		Value:      uint256.MustFromBig(tx.Value()),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+		BlobFeeCap: uint256.MustFromBig(tx.BlobGasFeeCap()),
+		BlobHashes: tx.BlobHashes(),
+		Sidecar:    tx.BlobTxSidecar(),
+	}
+	edit(inner)
+	return types.NewTx(inner), nil
+}
+
+func bumpFee(fee *big.Int) *uint256.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+blobPriceBump))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, big.NewInt(1))
+	}
+	return uint256.MustFromBig(bumped)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"blob/signer"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+)
+
+// sendCounter records every transaction sent to it.
+type sendCounter struct {
+	chainClient
+	sent []*types.Transaction
+}
+
+func (c *sendCounter) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.sent = append(c.sent, tx)
+	return nil
+}
+
+func newReplacerTestTx() *types.Transaction {
+	// The sidecar is never verified here, so it need not be valid.
+	sidecar := &types.BlobTxSidecar{
+		Blobs:       []kzg4844.Blob{{1}},
+		Commitments: []kzg4844.Commitment{{2}},
+		Proofs:      []kzg4844.Proof{{3}},
+	}
+	return types.NewTx(&types.BlobTx{
+		ChainID:    uint256.NewInt(1),
+		Nonce:      7,
+		GasTipCap:  uint256.NewInt(2e9),
+		GasFeeCap:  uint256.NewInt(3e10),
+		Gas:        100000,
+		To:         common.HexToAddress("0xF5106D4ef61cd0a04a345495f59f536bB7cd6074"),
+		Data:       []byte{0xca, 0xfe},
+		BlobFeeCap: uint256.NewInt(1e9),
+		BlobHashes: []common.Hash{{0x01, 4}},
+		Sidecar:    sidecar,
+	})
+}
+
+func TestBumpBlobTx(t *testing.T) {
+	tx := newReplacerTestTx()
+	bumped, err := bumpBlobTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bumped.Nonce() != tx.Nonce() || *bumped.To() != *tx.To() || bumped.Gas() != tx.Gas() || string(bumped.Data()) != string(tx.Data()) {
+		t.Fatal("replacement changed the call")
+	}
+	if len(bumped.BlobHashes()) != 1 || bumped.BlobHashes()[0] != tx.BlobHashes()[0] {
+		t.Fatalf("replacement lists blobs %v, want %v", bumped.BlobHashes(), tx.BlobHashes())
+	}
+	if sidecar := bumped.BlobTxSidecar(); sidecar == nil || sidecar.Blobs[0] != tx.BlobTxSidecar().Blobs[0] {
+		t.Fatal("replacement lost the sidecar")
+	}
+	for _, fee := range []struct {
+		name          string
+		before, after *big.Int
+	}{
+		{"tip cap", tx.GasTipCap(), bumped.GasTipCap()},
+		{"fee cap", tx.GasFeeCap(), bumped.GasFeeCap()},
+		{"blob fee cap", tx.BlobGasFeeCap(), bumped.BlobGasFeeCap()},
+	} {
+		if min := new(big.Int).Mul(fee.before, big.NewInt(2)); fee.after.Cmp(min) < 0 {
+			t.Errorf("%s raised from %v to %v, want at least %v", fee.name, fee.before, fee.after, min)
+		}
+	}
+
+	if _, err := bumpBlobTx(tx.WithoutBlobTxSidecar()); err == nil {
+		t.Fatal("bumped a blob transaction without its sidecar")
+	}
+}
+
+func TestBumpFeeRaisesZero(t *testing.T) {
+	if got := bumpFee(new(big.Int)); !got.Eq(uint256.NewInt(1)) {
+		t.Fatalf("bumped a zero fee to %v", got)
+	}
+}
+
+func TestReplaceMaxSpend(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx := newReplacerTestTx()
+	bumped, err := bumpBlobTx(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &sendCounter{}
+	m := &replacementManager{
+		client: client,
+		signer: signer.NewKey(key),
+		cfg:    replacementConfig{MaxSpend: new(big.Int).Sub(bumped.Cost(), big.NewInt(1))},
+	}
+	if _, err := m.replace(context.Background(), tx); !errors.Is(err, errMaxSpend) {
+		t.Fatalf("got %v, want %v", err, errMaxSpend)
+	}
+	if len(client.sent) != 0 {
+		t.Fatalf("sent %d transactions over the spend cap", len(client.sent))
+	}
+
+	m.cfg.MaxSpend = bumped.Cost()
+	replacement, err := m.replace(context.Background(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sent) != 1 || client.sent[0] != replacement {
+		t.Fatalf("sent %d transactions, want the replacement", len(client.sent))
+	}
+	if replacement.Nonce() != tx.Nonce() || replacement.BlobTxSidecar() == nil {
+		t.Fatal("replacement changed the nonce or lost the sidecar")
+	}
+}
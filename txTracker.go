@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type trackerConfig struct {
+	PollInterval  time.Duration
+	Confirmations uint64 // blocks on top of the inclusion block, counting it
+	MaxResubmits  int    // times to rebroadcast after the inclusion block is reorged out
+}
+
+func defaultTrackerConfig() trackerConfig {
+	return trackerConfig{
+		PollInterval:  4 * time.Second,
+		Confirmations: 3,
+		MaxResubmits:  3,
+	}
+}
+
+// trackTransaction waits until signedTx is mined and buried under the
+// configured number of confirmations. If the inclusion block is reorged out
+// in the meantime the transaction is broadcast again and tracking restarts.
+// A mined but reverted transaction is returned with an error.
+func trackTransaction(ctx context.Context, client chainClient, signedTx *types.Transaction, cfg trackerConfig) (*types.Receipt, error) {
+	resubmits := 0
+	for {
+		receipt, pinned, err := waitForReceipt(ctx, client, signedTx, cfg.PollInterval)
+		if err != nil {
+			return nil, err
+		}
+		logReceipt(receipt)
+
+		confirmed, err := waitForConfirmations(ctx, pinned, receipt, cfg)
+		if errors.Is(err, errEndpointLost) {
+			log.Printf("Lost the node that served the receipt of %s, fetching it again: %v", signedTx.Hash().Hex(), err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if confirmed {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return receipt, fmt.Errorf("transaction %s reverted in block %v", signedTx.Hash().Hex(), receipt.BlockNumber)
+			}
+			log.Printf("Transaction %s confirmed. block= %v confirmations= %d", signedTx.Hash().Hex(), receipt.BlockNumber, cfg.Confirmations)
+			return receipt, nil
+		}
+
+		if resubmits >= cfg.MaxResubmits {
+			return nil, fmt.Errorf("transaction %s reorged out %d times, giving up", signedTx.Hash().Hex(), resubmits+1)
+		}
+		resubmits++
+		log.Printf("Block %v containing %s was reorged out, resubmitting (%d/%d)", receipt.BlockNumber, signedTx.Hash().Hex(), resubmits, cfg.MaxResubmits)
+		err = client.SendTransaction(ctx, signedTx)
+		switch {
+		case err == nil || isKnownTxError(err):
+		case isNonceTooLowError(err):
+			// Either the new canonical chain includes the transaction too, or
+			// another one took its nonce and it can never be mined.
+			if err := checkNonceTaken(ctx, client, signedTx); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("failed to resubmit transaction: %v", err)
+		}
+	}
+}
+
+// errNonceTaken means a different transaction was mined at the nonce of the
+// one being tracked.
+var errNonceTaken = errors.New("nonce taken by another transaction")
+
+// checkNonceTaken is called when a rebroadcast of tx is refused with nonce
+// too low. It returns errNonceTaken if the sender's mined nonce is past tx's
+// while tx itself has no receipt, and nil if tx was mined or the node has
+// not caught up yet.
+func checkNonceTaken(ctx context.Context, client chainClient, tx *types.Transaction) error {
+	if receipt, err := client.TransactionReceipt(ctx, tx.Hash()); err == nil {
+		log.Printf("Transaction %s was mined again in block %v", tx.Hash().Hex(), receipt.BlockNumber)
+		return nil
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return fmt.Errorf("failed to recover sender of %s: %v", tx.Hash().Hex(), err)
+	}
+	mined, err := client.NonceAt(ctx, from, nil)
+	if err != nil {
+		return fmt.Errorf("error getting nonce: %v", err)
+	}
+	if mined > tx.Nonce() {
+		return fmt.Errorf("%w: nonce %d of %s is used and %s has no receipt", errNonceTaken, tx.Nonce(), from.Hex(), tx.Hash().Hex())
+	}
+	log.Printf("Nonce of %s is already used, waiting for its receipt", tx.Hash().Hex())
+	return nil
+}
+
+// receiptPinner is implemented by clients spreading reads over several nodes.
+// See rpcPool.receiptPinned.
+type receiptPinner interface {
+	receiptPinned(ctx context.Context, hash common.Hash) (*types.Receipt, chainClient, error)
+}
+
+// waitForReceipt polls until a receipt for tx is available. It also returns
+// the client to check the receipt's confirmations with: client itself, or
+// the one node of it that served the receipt.
+func waitForReceipt(ctx context.Context, client chainClient, tx *types.Transaction, interval time.Duration) (*types.Receipt, chainClient, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var (
+			receipt *types.Receipt
+			pinned  = client
+			err     error
+		)
+		if pinner, ok := client.(receiptPinner); ok {
+			receipt, pinned, err = pinner.receiptPinned(ctx, tx.Hash())
+		} else {
+			receipt, err = client.TransactionReceipt(ctx, tx.Hash())
+		}
+		if err == nil {
+			return receipt, pinned, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			log.Printf("Failed to fetch receipt of %s: %v", tx.Hash().Hex(), err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// errEndpointLost means the node answering confirmation checks stopped
+// responding, so they have to start over with a fresh receipt.
+var errEndpointLost = errors.New("node stopped responding")
+
+// waitForConfirmations blocks until the receipt's block has enough blocks on
+// top of it. It returns false as soon as the block is no longer canonical,
+// and errEndpointLost if client stops answering.
+func waitForConfirmations(ctx context.Context, client chainClient, receipt *types.Receipt, cfg trackerConfig) (bool, error) {
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	target := new(big.Int).Add(receipt.BlockNumber, new(big.Int).SetUint64(cfg.Confirmations))
+	target.Sub(target, big.NewInt(1))
+	for {
+		header, err := client.HeaderByNumber(ctx, receipt.BlockNumber)
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			return false, nil
+		case err != nil && !isNodeAnswer(err):
+			return false, fmt.Errorf("%w: %v", errEndpointLost, err)
+		case err != nil:
+			log.Printf("Failed to fetch block %v: %v", receipt.BlockNumber, err)
+		case header.Hash() != receipt.BlockHash:
+			return false, nil
+		default:
+			head, err := client.BlockNumber(ctx)
+			if err != nil && !isNodeAnswer(err) {
+				return false, fmt.Errorf("%w: %v", errEndpointLost, err)
+			} else if err != nil {
+				log.Printf("Failed to fetch head block number: %v", err)
+			} else if new(big.Int).SetUint64(head).Cmp(target) >= 0 {
+				return true, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func logReceipt(receipt *types.Receipt) {
+	status := "success"
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		status = "reverted"
+	}
+	log.Printf("Transaction %s mined. status= %s block= %v gasUsed= %d effectiveGasPrice= %v blobGasUsed= %d blobGasPrice= %v",
+		receipt.TxHash.Hex(), status, receipt.BlockNumber, receipt.GasUsed, receipt.EffectiveGasPrice, receipt.BlobGasUsed, receipt.BlobGasPrice)
+}
+
+// isNonceTooLowError reports whether a broadcast failed because the sender's
+// nonce has moved past the transaction's.
+func isNonceTooLowError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
+
+// isKnownTxError reports whether a broadcast failed only because the node
+// already has the transaction.
+func isKnownTxError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "already imported")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// reorgClient mines a transaction in block 10, reorgs that block out as soon
+// as it is looked at and includes the transaction again in block 11.
+type reorgClient struct {
+	chainClient
+	reorged bool
+	sends   int
+}
+
+func (c *reorgClient) header(number uint64) *types.Header {
+	extra := []byte("old")
+	if c.reorged {
+		extra = []byte("new")
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(number), Extra: extra}
+}
+
+func (c *reorgClient) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	number := uint64(10)
+	if c.reorged {
+		number = 11
+	}
+	return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful, BlockNumber: new(big.Int).SetUint64(number), BlockHash: c.header(number).Hash()}, nil
+}
+
+func (c *reorgClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	c.reorged = true
+	return c.header(number.Uint64()), nil
+}
+
+func (c *reorgClient) BlockNumber(ctx context.Context) (uint64, error) {
+	return 20, nil
+}
+
+func (c *reorgClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.sends++
+	return errors.New("nonce too low: next nonce 5, tx nonce 4")
+}
+
+func TestTrackTransactionNonceTooLowAfterReorg(t *testing.T) {
+	client := &reorgClient{}
+	tx := types.NewTx(&types.LegacyTx{Nonce: 4})
+	cfg := trackerConfig{PollInterval: time.Millisecond, Confirmations: 3, MaxResubmits: 3}
+
+	receipt, err := trackTransaction(context.Background(), client, tx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.BlockNumber.Uint64() != 11 || client.sends != 1 {
+		t.Fatalf("confirmed in block %v after %d resubmits", receipt.BlockNumber, client.sends)
+	}
+}
+
+// replacedClient mines a transaction in block 10, then reorgs it out in
+// favour of a chain where a different transaction used the same nonce.
+type replacedClient struct {
+	reorgClient
+	from common.Address
+}
+
+func (c *replacedClient) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	if c.reorged {
+		return nil, ethereum.NotFound
+	}
+	return c.reorgClient.TransactionReceipt(ctx, hash)
+}
+
+func (c *replacedClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	if account != c.from {
+		return 0, nil
+	}
+	return 5, nil
+}
+
+func TestTrackTransactionNonceTaken(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	tx := types.MustSignNewTx(key, signer, &types.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 4, Gas: 21000})
+	client := &replacedClient{from: crypto.PubkeyToAddress(key.PublicKey)}
+	cfg := trackerConfig{PollInterval: time.Millisecond, Confirmations: 3, MaxResubmits: 3}
+
+	receipt, err := trackTransaction(context.Background(), client, tx, cfg)
+	if !errors.Is(err, errNonceTaken) {
+		t.Fatalf("got receipt %v and error %v, want %v", receipt, err, errNonceTaken)
+	}
+	if client.sends != 1 {
+		t.Fatalf("resubmitted %d times", client.sends)
+	}
+}
@@ -2,129 +2,103 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
-	"log"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/crypto/kzg4844"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
 
-func prepareTransactionParams(client *ethclient.Client, privateKey *ecdsa.PrivateKey) (uint64, *big.Int, *big.Int, *uint256.Int, error) {
-	publicKey := privateKey.PublicKey
-	fromAddress := crypto.PubkeyToAddress(publicKey)
-
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
-	if err != nil {
-		return 0, nil, nil, nil, fmt.Errorf("error getting nonce: %v", err)
-	}
+// txParams holds what a new transaction needs from the node.
+type txParams struct {
+	Nonce        uint64
+	ChainID      *big.Int
+	Tip          *big.Int
+	MaxFeePerGas *uint256.Int
+	BlobFeeCap   *uint256.Int
+	Head         *types.Header // latest block the fees were derived from
+}
 
+// prepareTransactionParams reads fees and the chain id from the node. The
+// nonce is left to the caller, which reserves it from a nonceManager.
+func prepareTransactionParams(client chainClient, blobFees blobFeeStrategy) (*txParams, error) {
 	suggestedTip, err := client.SuggestGasTipCap(context.Background())
 	if err != nil {
-		return 0, nil, nil, nil, fmt.Errorf("error suggesting gas tip cap: %v", err)
+		return nil, fmt.Errorf("error suggesting gas tip cap: %v", err)
 	}
 	header, err := client.HeaderByNumber(context.Background(), nil) // nil for latest block
 	if err != nil {
-		return 0, nil, nil, nil, fmt.Errorf("error fetching latest block header: %v", err)
+		return nil, fmt.Errorf("error fetching latest block header: %v", err)
 	}
 
 	maxFeePerGas := new(big.Int).Add(header.BaseFee, suggestedTip)
 
-	chainID, err := client.NetworkID(context.Background())
+	blobFeeCap, err := blobFees.BlobFeeCap(header)
 	if err != nil {
-		return 0, nil, nil, nil, fmt.Errorf("error fetching chain id: %v", err)
+		return nil, fmt.Errorf("error computing blob fee cap: %v", err)
 	}
 
-	return nonce, chainID, suggestedTip, uint256.MustFromBig(maxFeePerGas), nil
-}
-
-func createBlobTx(chainID *big.Int, nonce uint64, tip *big.Int, maxFeePerGas *uint256.Int, root []byte, input []byte) (*types.Transaction, error) {
-	// blobs, commits, proofs, err := EncodeBlobs(root)
-
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-	emptyBlob := kzg4844.Blob{}
-	emptyBlobCommit, err := kzg4844.BlobToCommitment(emptyBlob)
-	if err != nil {
-		log.Fatal("Failed to create commitment", "err", err)
-	}
-	emptyBlobProof, err := kzg4844.ComputeBlobProof(emptyBlob, emptyBlobCommit)
+	chainID, err := client.NetworkID(context.Background())
 	if err != nil {
-		log.Fatal("Failed to create proof", "err", err)
+		return nil, fmt.Errorf("error fetching chain id: %v", err)
 	}
 
-	sidecar := types.BlobTxSidecar{
-		Blobs:       []kzg4844.Blob{emptyBlob},
-		Commitments: []kzg4844.Commitment{emptyBlobCommit},
-		Proofs:      []kzg4844.Proof{emptyBlobProof},
-	}
+	return &txParams{
+		ChainID:      chainID,
+		Tip:          suggestedTip,
+		MaxFeePerGas: uint256.MustFromBig(maxFeePerGas),
+		BlobFeeCap:   blobFeeCap,
+		Head:         header,
+	}, nil
+}
 
+func createBlobTx(p *txParams, nonce uint64, sidecar *types.BlobTxSidecar, input []byte) (*types.Transaction, error) {
 	return types.NewTx(&types.BlobTx{
-		ChainID:    uint256.MustFromBig(chainID),
+		ChainID:    uint256.MustFromBig(p.ChainID),
 		Nonce:      nonce,
-		GasTipCap:  uint256.MustFromBig(tip),
-		GasFeeCap:  maxFeePerGas,
+		GasTipCap:  uint256.MustFromBig(p.Tip),
+		GasFeeCap:  p.MaxFeePerGas,
 		Gas:        2500000,
 		To:         common.HexToAddress(TO_ADDRESS),
 		Value:      uint256.NewInt(0),
 		Data:       input,
-		BlobFeeCap: uint256.NewInt(3e10),
+		BlobFeeCap: p.BlobFeeCap,
 		BlobHashes: sidecar.BlobHashes(),
-		Sidecar:    &sidecar,
+		Sidecar:    sidecar,
 	}), nil
 }
 
-func encodeBlobs(data []byte) []kzg4844.Blob {
-	blobs := []kzg4844.Blob{{}}
-	blobIndex := 0
-	fieldIndex := -1
-	for i := 0; i < len(data); i += 31 {
-		fieldIndex++
-		if fieldIndex == params.BlobTxFieldElementsPerBlob {
-			blobs = append(blobs, kzg4844.Blob{})
-			blobIndex++
-			fieldIndex = 0
-		}
-		max := i + 31
-		if max > len(data) {
-			max = len(data)
-		}
-		copy(blobs[blobIndex][fieldIndex*32:], data[i:max])
-	}
-	return blobs
-}
-
-func EncodeBlobs(data []byte) ([]kzg4844.Blob, []kzg4844.Commitment, []kzg4844.Proof, error) {
-	var (
-		blobs   []kzg4844.Blob
-		commits []kzg4844.Commitment
-		proofs  []kzg4844.Proof
-	)
-
-	for _, blob := range blobs {
-
-		commit, err := kzg4844.BlobToCommitment(blob)
+// resultArguments is the argument layout of submitResult, reused to serialize
+// the solver output into blobs.
+func resultArguments() abi.Arguments {
+	bytes32Ty, _ := abi.NewType("bytes32", "", nil)
+	matrixTy, _ := abi.NewType("uint256[3][3]", "", nil)
+	uint256Ty, _ := abi.NewType("uint256", "", nil)
 
-		if err != nil {
-			return nil, nil, nil, err
-		}
-
-		commits = append(commits, commit)
+	return abi.Arguments{{Type: bytes32Ty}, {Type: matrixTy}, {Type: uint256Ty}}
+}
 
-		proof, err := kzg4844.ComputeBlobProof(blob, commit)
+// serializeResult ABI-encodes the solver output (root, result matrix and
+// request id) so off-chain verifiers can decode it from the blob with the
+// same argument layout as submitResult.
+func serializeResult(root []byte, matrixMul [3][3]*big.Int, requestId *big.Int) ([]byte, error) {
+	var root32 [32]byte
+	copy(root32[:], root)
 
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		proofs = append(proofs, proof)
+	payload, err := resultArguments().Pack(root32, matrixMul, requestId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize result: %v", err)
+	}
+	return payload, nil
+}
 
+// deserializeResult reverses serializeResult.
+func deserializeResult(payload []byte) ([32]byte, [3][3]*big.Int, *big.Int, error) {
+	values, err := resultArguments().Unpack(payload)
+	if err != nil {
+		return [32]byte{}, [3][3]*big.Int{}, nil, fmt.Errorf("failed to deserialize result: %v", err)
 	}
-	return blobs, commits, proofs, nil
+	return values[0].([32]byte), values[1].([3][3]*big.Int), values[2].(*big.Int), nil
 }